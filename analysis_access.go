@@ -0,0 +1,119 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// varAccess 要锁的变量的一次访问
+type varAccess struct {
+	token.Position
+	key           lockKey // 要持有的 mutex （访问所在函数中的实例）
+	write         bool    // 写操作，需要 Lock
+	rlocked       bool    // 写操作，但只持有 RLock
+	otherInstance bool    // 持有的是同一字段其他实例的 mutex
+	iter          bool    // range 遍历
+	desc          string  // 经嵌入结构体访问时，完整的选择器路径（如 o.Inner.a ）
+}
+
+// name 报错时显示的变量名
+func (access varAccess) name(v *types.Var) string {
+	if access.desc != "" {
+		return access.desc
+	}
+	return v.Name()
+}
+
+// isWriteAccess 判断指令 instr 对 v （变量地址）的使用是否是写操作
+func isWriteAccess(instr ssa.Instruction, v ssa.Value) bool {
+	switch instr := instr.(type) {
+	case *ssa.Store:
+		return instr.Addr == v
+	case *ssa.UnOp:
+		return instr.Op == token.MUL && instr.X == v && isValueWritten(instr)
+	case *ssa.FieldAddr:
+		return instr.X == v && isAddrWritten(instr)
+	case *ssa.IndexAddr:
+		return instr.X == v && isAddrWritten(instr)
+	}
+	return false
+}
+
+// isAddrWritten 地址 addr 指向的内容（包括子字段、元素）是否被写入
+func isAddrWritten(addr ssa.Value) bool {
+	refs := addr.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, r := range *refs {
+		if isWriteAccess(r, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValueWritten 引用类型的值（map slice 指针）指向的内容是否被写入
+func isValueWritten(v ssa.Value) bool {
+	refs := v.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, r := range *refs {
+		if isValueWrittenBy(r, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValueWrittenBy 指令 r 是否通过引用类型的值 v 写入
+func isValueWrittenBy(r ssa.Instruction, v ssa.Value) bool {
+	switch r := r.(type) {
+	case *ssa.MapUpdate:
+		return r.Map == v
+	case *ssa.Store:
+		return r.Addr == v
+	case *ssa.IndexAddr:
+		return r.X == v && isAddrWritten(r)
+	case *ssa.FieldAddr:
+		return r.X == v && isAddrWritten(r)
+	case *ssa.Call:
+		if b, ok := r.Call.Value.(*ssa.Builtin); ok && (b.Name() == "delete" || b.Name() == "clear") {
+			return len(r.Call.Args) > 0 && r.Call.Args[0] == v
+		}
+	case *ssa.ChangeType:
+		return isValueWritten(r)
+	case *ssa.Slice:
+		return isValueWritten(r)
+	}
+	return false
+}
+
+// isRangeAccess 指令是否是 range 语句中对被遍历变量的访问
+func isRangeAccess(instr ssa.Instruction) bool {
+	pos := instr.Pos()
+	if !pos.IsValid() || instr.Parent() == nil || instr.Parent().Syntax() == nil {
+		return false
+	}
+	var find bool
+	ast.Inspect(instr.Parent().Syntax(), func(n ast.Node) bool {
+		if rangeStmt, ok := n.(*ast.RangeStmt); ok && rangeStmt.X.Pos() <= pos && pos < rangeStmt.X.End() {
+			find = true
+		}
+		return !find
+	})
+	return find
+}
+
+// globalOf 获取全局变量对应的 ssa.Global
+func globalOf(prog *ssa.Program, v *types.Var) *ssa.Global {
+	pkg := prog.Package(v.Pkg())
+	if pkg == nil {
+		return nil
+	}
+	return pkg.Var(v.Name())
+}
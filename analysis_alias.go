@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// checkAliases 检查加锁时取出的要锁变量（map slice 指针），经局部变量、phi 、结构体拷贝传递后，
+// 解锁后再使用（读写元素、解引用）
+func (analyzer *BaseAnalyzer) checkAliases() {
+	for _, node := range analyzer.cg.Nodes {
+		if !analyzer.inPkgs(node.Func) {
+			continue
+		}
+		for _, block := range node.Func.Blocks {
+			for _, instr := range block.Instrs {
+				load, ok := instr.(*ssa.UnOp)
+				if !ok {
+					continue
+				}
+				myvar, key, _ := analyzer.guardedSource(load)
+				if myvar == nil {
+					continue
+				}
+				// 取值时没有加锁的，在变量访问检查中报错
+				if !analyzer.locks.isLocked(load, key, false) {
+					continue
+				}
+				loadPos := analyzer.prog.Fset.Position(load.Pos())
+				for _, use := range aliasUses(load, map[ssa.Value]bool{}) {
+					pos := analyzer.prog.Fset.Position(use.Pos())
+					if !pos.IsValid() || nolint(getComment(pos)) {
+						continue
+					}
+					if analyzer.locks.isLocked(use, key, false) {
+						continue
+					}
+					op := "使用了"
+					if _, ok := use.(*ssa.Range); ok || isRangeAccess(use) {
+						op = " range 遍历"
+					}
+					analyzer.PrintsCall = append(analyzer.PrintsCall, fmt.Sprintf("[mutex check] %v:%v 解锁后%v %v 的引用，没有持有 %v （取值位置 %v:%v）。", pos.Filename, pos.Line, op, myvar.Name(), key.m.Name(), loadPos.Filename, loadPos.Line))
+				}
+			}
+		}
+	}
+}
+
+// aliasUses 沿 SSA 值传递（局部变量、phi 、类型转换、结构体拷贝），找到对引用 v 的所有使用
+func aliasUses(v ssa.Value, seen map[ssa.Value]bool) (uses []ssa.Instruction) {
+	if seen[v] {
+		return
+	}
+	seen[v] = true
+	refs := v.Referrers()
+	if refs == nil {
+		return
+	}
+	for _, r := range *refs {
+		switch r := r.(type) {
+		case *ssa.Phi:
+			uses = append(uses, aliasUses(r, seen)...)
+		case *ssa.ChangeType:
+			uses = append(uses, aliasUses(r, seen)...)
+		case *ssa.Slice:
+			if r.X == v {
+				uses = append(uses, aliasUses(r, seen)...)
+			}
+		case *ssa.Store:
+			if r.Val == v {
+				uses = append(uses, storedAliasUses(r, "", seen)...)
+			}
+		case *ssa.MapUpdate:
+			if r.Map == v {
+				uses = append(uses, r)
+			}
+		case *ssa.Lookup:
+			if r.X == v {
+				uses = append(uses, r)
+			}
+		case *ssa.IndexAddr:
+			if r.X == v {
+				uses = append(uses, r)
+			}
+		case *ssa.FieldAddr:
+			if r.X == v {
+				uses = append(uses, r)
+			}
+		case *ssa.Range:
+			if r.X == v {
+				uses = append(uses, r)
+			}
+		case *ssa.UnOp:
+			if r.X == v {
+				uses = append(uses, r)
+			}
+		case *ssa.Call:
+			// len delete append 等内置函数；传给其他函数的，由形参检查处理
+			if _, ok := r.Call.Value.(*ssa.Builtin); ok {
+				uses = append(uses, r)
+			}
+		}
+	}
+	return
+}
+
+// storedAliasUses 引用（或含有引用的结构体，引用位于字段路径 suffix ）被保存到局部变量后，从中再取出的使用
+func storedAliasUses(store *ssa.Store, suffix string, seen map[ssa.Value]bool) (uses []ssa.Instruction) {
+	root, path := instanceOf(store.Addr)
+	if _, ok := root.(*ssa.Alloc); !ok {
+		return
+	}
+	path += suffix
+	for _, block := range store.Parent().Blocks {
+		for _, instr := range block.Instrs {
+			load, ok := instr.(*ssa.UnOp)
+			if !ok {
+				continue
+			}
+			r, p := instanceOf(load.X)
+			if r != root || !pathHasPrefix(path, p) {
+				continue
+			}
+			if p == path {
+				uses = append(uses, aliasUses(load, seen)...)
+			} else {
+				// 取出的是整个结构体，继续查找对应的字段
+				uses = append(uses, fieldAliasUses(load, path[len(p):], seen)...)
+			}
+		}
+	}
+	return
+}
+
+// fieldAliasUses 结构体值 v 中，字段路径 path （如 .a.b ）上的引用的使用
+func fieldAliasUses(v ssa.Value, path string, seen map[ssa.Value]bool) (uses []ssa.Instruction) {
+	if path == "" {
+		return aliasUses(v, seen)
+	}
+	if strings.Contains(path, "*") {
+		return
+	}
+	name := strings.SplitN(path[1:], ".", 2)[0]
+	refs := v.Referrers()
+	if refs == nil {
+		return
+	}
+	for _, r := range *refs {
+		switch r := r.(type) {
+		case *ssa.Field:
+			if st, ok := r.X.Type().Underlying().(*types.Struct); ok && r.X == v && st.Field(r.Field).Name() == name {
+				uses = append(uses, fieldAliasUses(r, path[len(name)+1:], seen)...)
+			}
+		case *ssa.Store:
+			// 结构体拷贝
+			if r.Val == v {
+				uses = append(uses, storedAliasUses(r, path, seen)...)
+			}
+		}
+	}
+	return
+}
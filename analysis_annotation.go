@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// annotName mutex 注释中的一个变量名，及其在注释中的位置
+type annotName struct {
+	name string
+	pos  token.Pos
+}
+
+// mutexAnnotation mutex 声明上的注释
+type mutexAnnotation struct {
+	found  bool // 有注释（ nolint 也算）
+	nolint bool
+	names  []annotName
+}
+
+// 变量名：名字 { .名字 | [*] } ，如 a stats.hits items[*].state
+var annotNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\[\*\])?(\.[A-Za-z_][A-Za-z0-9_]*(\[\*\])?)*$`)
+
+// parseMutexAnnotation 解析 mutex 的注释。语法：
+//
+//	[protects:] 变量 {, 变量} [(理由)]
+//
+// 行尾注释可以省略 protects: ；上方的文档注释只识别 protects: 开头的行，其他行视为说明文字。
+// 格式错误逐个报错，不影响其他变量
+func parseMutexAnnotation(fset *token.FileSet, doc, trailing *ast.CommentGroup) (a mutexAnnotation) {
+	for _, cg := range []*ast.CommentGroup{doc, trailing} {
+		if cg == nil {
+			continue
+		}
+		for _, l := range cg.List {
+			if nolint(l.Text) {
+				a.found, a.nolint = true, true
+				continue
+			}
+			text, offset := commentText(l)
+			if rest := strings.TrimPrefix(text, "protects:"); rest != text {
+				offset += len(text) - len(rest)
+				text = rest
+			} else if cg == doc {
+				continue
+			}
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			a.found = true
+			a.names = append(a.names, parseAnnotNames(fset, text, l.Slash+token.Pos(offset))...)
+		}
+	}
+	return
+}
+
+// commentText 注释的内容（去掉 // /* */ 及首尾空白），以及内容在注释中的偏移
+func commentText(c *ast.Comment) (string, int) {
+	text := c.Text
+	offset := 2
+	if strings.HasPrefix(text, "/*") {
+		text = strings.TrimSuffix(text[2:], "*/")
+	} else {
+		text = text[2:]
+	}
+	trimmed := strings.TrimLeft(text, " \t")
+	offset += len(text) - len(trimmed)
+	return strings.TrimRight(trimmed, " \t"), offset
+}
+
+// directiveNames 注释中以 prefix 开头的行（如 guarded_by: +checklocks: ），按 parseAnnotNames 解析其后的变量列表
+func directiveNames(fset *token.FileSet, cg *ast.CommentGroup, prefix string) (names []annotName) {
+	if cg == nil {
+		return
+	}
+	for _, l := range cg.List {
+		text, offset := commentText(l)
+		if rest := strings.TrimPrefix(text, prefix); rest != text {
+			names = append(names, parseAnnotNames(fset, rest, l.Slash+token.Pos(offset+len(prefix)))...)
+		}
+	}
+	return
+}
+
+// parseAnnotNames 解析逗号分隔的变量列表，以及结尾括号中的理由。 fset 为 nil 时不报错（同一注释只报一次）
+func parseAnnotNames(fset *token.FileSet, text string, base token.Pos) (names []annotName) {
+	if i := strings.Index(text, "("); i >= 0 {
+		if !strings.HasSuffix(text, ")") && fset != nil {
+			pos := fset.Position(base + token.Pos(i))
+			fmt.Printf("[mutex check] %v:%v mutex 注释中的理由没有以 ) 结束\n", pos.Filename, pos.Line)
+		}
+		text = text[:i]
+	}
+	offset := 0
+	for _, part := range strings.Split(text, ",") {
+		name := strings.TrimSpace(part)
+		pos := base + token.Pos(offset+strings.Index(part, name))
+		offset += len(part) + 1
+		if name == "" {
+			if strings.TrimSpace(text) != "" && fset != nil {
+				p := fset.Position(pos)
+				fmt.Printf("[mutex check] %v:%v mutex 注释中有空的变量名\n", p.Filename, p.Line)
+			}
+			continue
+		}
+		if !annotNameRe.MatchString(name) {
+			if fset == nil {
+				continue
+			}
+			p := fset.Position(pos)
+			fmt.Printf("[mutex check] %v:%v mutex 注释中的 %q 不是变量名，说明文字请写在括号中\n", p.Filename, p.Line, name)
+			continue
+		}
+		names = append(names, annotName{name: name, pos: pos})
+	}
+	return
+}
+
+// printUndeclared 注释中的变量未声明，给出最接近的候选
+func printUndeclared(fset *token.FileSet, n annotName, candidates []string) {
+	pos := fset.Position(n.pos)
+	msg := fmt.Sprintf("[mutex check] %v:%v mutex 变量注释中的变量 %v ，未声明", pos.Filename, pos.Line, n.name)
+	if s := suggest(n.name, candidates); s != "" {
+		msg += fmt.Sprintf("，是否是 %v ？", s)
+	}
+	fmt.Println(msg)
+}
+
+// suggest 拼写最接近的候选（编辑距离不超过 2 ，且小于名字长度的一半）
+func suggest(name string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(name, c); d < bestDist && d*2 < len(name) {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"go/ast"
 	"go/token"
 
 	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
 )
 
 var comments = map[token.Position]string{}
@@ -30,3 +32,19 @@ func getComment(pos token.Position) string {
 	}
 	return ""
 }
+
+// funcDoc 函数的文档注释，没有时返回 nil
+func funcDoc(fn *ssa.Function) *ast.CommentGroup {
+	if fn == nil {
+		return nil
+	}
+	if decl, ok := fn.Syntax().(*ast.FuncDecl); ok {
+		return decl.Doc
+	}
+	return nil
+}
+
+// ignoredFunc 函数注释了 nolint: mutex_check 或 +checklocksignore ，不检查函数内的访问
+func ignoredFunc(fn *ssa.Function) bool {
+	return ignored(funcDoc(fn))
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// contractLock 函数约定中的一个 mutex
+type contractLock struct {
+	key  lockKey // 被调函数中的 key
+	kind lockKind
+	expr string // 注释中的写法，如 s.mu
+}
+
+// funcContract 函数注释中的加锁约定：
+//
+//	// mutex_check: requires s.mu        调用前必须持有 Lock
+//	// mutex_check: requires_read s.mu   调用前必须持有 RLock 或 Lock
+//	// mutex_check: acquires s.mu        返回时持有
+//	// mutex_check: releases s.mu        调用前持有，函数内解锁
+//
+// 也兼容 gVisor checklocks 的 +checklocks +checklocksread +checklocksacquire(read) +checklocksrelease(read)
+type funcContract struct {
+	requires []contractLock
+	acquires []contractLock
+	releases []contractLock
+}
+
+// 所有函数的约定，只解析一次
+var contracts = map[*ssa.Function]*funcContract{}
+
+// gVisor checklocks 函数注释对应的约定
+var checklocksVerbs = map[string]string{
+	"+checklocks":            "requires",
+	"+checklocksread":        "requires_read",
+	"+checklocksacquire":     "acquires",
+	"+checklocksacquireread": "acquires_read",
+	"+checklocksrelease":     "releases",
+	"+checklocksreleaseread": "releases_read",
+}
+
+// 按命名约定（如 xxxLocked ）推断约定的函数名，由 --lockedpattern 指定，为空时不推断
+var lockedPattern *regexp.Regexp
+
+func parseLockedPattern(s string) error {
+	if s == "" {
+		return nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return fmt.Errorf("lockedpattern 格式错误：%v", err)
+	}
+	lockedPattern = re
+	return nil
+}
+
+// contractOf 函数的加锁约定，没有时返回 nil
+func contractOf(fn *ssa.Function) *funcContract {
+	if c, ok := contracts[fn]; ok {
+		return c
+	}
+	c := parseContract(fn)
+	if c == nil && lockedPattern != nil && lockedPattern.MatchString(fn.Name()) {
+		c = impliedContract(fn)
+	}
+	contracts[fn] = c
+	return c
+}
+
+func parseContract(fn *ssa.Function) *funcContract {
+	doc := funcDoc(fn)
+	if doc == nil || fn.Pkg == nil {
+		return nil
+	}
+	var c *funcContract
+	for _, l := range doc.List {
+		// 约定的动词，以及其后的 mutex 列表（语法与 mutex 注释相同，可以有括号中的理由）
+		text, offset := commentText(l)
+		var verb, rest string
+		if r := strings.TrimPrefix(text, "mutex_check:"); r != text {
+			r = strings.TrimLeft(r, " \t")
+			i := strings.IndexAny(r, " \t")
+			if i < 0 {
+				continue
+			}
+			verb, rest = r[:i], r[i:]
+		} else if i := strings.Index(text, ":"); i > 0 && checklocksVerbs[text[:i]] != "" {
+			verb, rest = checklocksVerbs[text[:i]], text[i+1:]
+		} else {
+			continue
+		}
+		offset += len(text) - len(rest)
+		for _, n := range parseAnnotNames(fn.Prog.Fset, rest, l.Slash+token.Pos(offset)) {
+			expr := n.name
+			pos := fn.Prog.Fset.Position(n.pos)
+			k, ok := contractKey(fn, expr)
+			if !ok {
+				if mutexParam(fn, expr) {
+					fmt.Printf("[mutex check] %v:%v mutex_check 注释中的 mutex %v 是参数本身，不支持，请写 mutex 所在的结构体字段或全局变量\n", pos.Filename, pos.Line, expr)
+					continue
+				}
+				fmt.Printf("[mutex check] %v:%v mutex_check 注释中的 mutex %v ，未声明\n", pos.Filename, pos.Line, expr)
+				continue
+			}
+			if c == nil {
+				c = &funcContract{}
+			}
+			lock := contractLock{key: k, kind: lockWrite, expr: expr}
+			switch verb {
+			case "requires":
+				c.requires = append(c.requires, lock)
+			case "requires_read":
+				lock.kind = lockRead
+				c.requires = append(c.requires, lock)
+			case "acquires":
+				c.acquires = append(c.acquires, lock)
+			case "acquires_read":
+				lock.kind = lockRead
+				c.acquires = append(c.acquires, lock)
+			case "releases":
+				c.releases = append(c.releases, lock)
+			case "releases_read":
+				lock.kind = lockRead
+				c.releases = append(c.releases, lock)
+			default:
+				fmt.Printf("[mutex check] %v:%v mutex_check 注释不支持 %v ，应为 requires requires_read acquires acquires_read releases releases_read\n", pos.Filename, pos.Line, verb)
+			}
+		}
+	}
+	return c
+}
+
+// impliedContract 命名约定推断的约定：调用者持有接收者（或第一个参数）的 mutex 。
+// 函数内写入了接收者的字段时要求 Lock ，否则 RLock 即可
+func impliedContract(fn *ssa.Function) *funcContract {
+	if len(fn.Params) == 0 || fn.Syntax() == nil {
+		return nil
+	}
+	recv := fn.Params[0]
+	p, ok := recv.Type().Underlying().(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	st, ok := p.Elem().Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	var mutexes []*types.Var
+	for i := 0; i < st.NumFields(); i++ {
+		if isMutexType(st.Field(i).Type()) {
+			mutexes = append(mutexes, st.Field(i))
+		}
+	}
+	if len(mutexes) != 1 {
+		if len(mutexes) > 1 {
+			pos := fn.Prog.Fset.Position(fn.Pos())
+			fmt.Printf("[mutex check] %v:%v %v 的 %v 有多个 mutex ，请使用 mutex_check: requires 注释指明\n", pos.Filename, pos.Line, fn.Name(), recv.Name())
+		}
+		return nil
+	}
+	kind := lockRead
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			if fa, ok := instr.(*ssa.FieldAddr); ok && fa.X == recv && isAddrWritten(fa) {
+				kind = lockWrite
+			}
+		}
+	}
+	return &funcContract{requires: []contractLock{{
+		key:  lockKey{m: mutexes[0], root: recv},
+		kind: kind,
+		expr: recv.Name() + "." + mutexes[0].Name(),
+	}}}
+}
+
+// contractKey 解析约定中的 mutex ：参数（包括接收者）的字段 s.mu s.inner.mu ，全局 mutex mu pkg.mu ，全局变量的字段 mgr.mu 。
+// 不支持参数本身是 mutex （ requires m ），这样的 mutex 没有对应的变量，无法与调用处的加锁对应
+func contractKey(fn *ssa.Function, expr string) (lockKey, bool) {
+	segs := strings.Split(expr, ".")
+	var root ssa.Value
+	var addr types.Type // 指向当前结构体的指针类型
+	path := ""
+	for _, p := range fn.Params {
+		if p.Name() == segs[0] {
+			root, addr = p, p.Type()
+		}
+	}
+	if root == nil {
+		// 全局变量可以在 import 的包中（ reg.Mu ），包名按函数所在文件的 import 解析
+		path, parts := guardPkg(fn.Pkg.Pkg, funcFileScope(fn), expr)
+		pkg := fn.Prog.ImportedPackage(path)
+		if pkg == nil {
+			return lockKey{}, false
+		}
+		segs = parts
+		g, ok := pkg.Members[segs[0]].(*ssa.Global)
+		if !ok {
+			return lockKey{}, false
+		}
+		m := g.Object().(*types.Var)
+		if len(segs) == 1 {
+			return lockKey{m: m}, isMutexType(m.Type())
+		}
+		root, addr = g, g.Type()
+		if _, ok := m.Type().Underlying().(*types.Pointer); ok {
+			// 全局变量本身是指针，先取值
+			path, addr = "*", m.Type()
+		}
+	}
+	var m *types.Var
+	for _, seg := range segs[1:] {
+		if m != nil {
+			addr = stepInto(m, &path)
+		}
+		obj, index, _ := types.LookupFieldOrMethod(addr, false, fn.Pkg.Pkg, seg)
+		if _, ok := obj.(*types.Var); !ok {
+			return lockKey{}, false
+		}
+		// 经嵌入结构体提升的字段，逐层展开
+		for i, idx := range index {
+			if i > 0 {
+				addr = stepInto(m, &path)
+			}
+			p, ok := addr.Underlying().(*types.Pointer)
+			if !ok {
+				return lockKey{}, false
+			}
+			st, ok := p.Elem().Underlying().(*types.Struct)
+			if !ok {
+				return lockKey{}, false
+			}
+			m = st.Field(idx)
+		}
+	}
+	if m == nil || !isMutexType(m.Type()) {
+		return lockKey{}, false
+	}
+	return lockKey{m: m, root: root, path: path}, true
+}
+
+// funcFileScope 函数 fn 所在文件的作用域（ import 的包名）
+func funcFileScope(fn *ssa.Function) *types.Scope {
+	pkgScope := fn.Pkg.Pkg.Scope()
+	s := pkgScope.Innermost(fn.Pos())
+	for s != nil && s.Parent() != pkgScope {
+		s = s.Parent()
+	}
+	return s
+}
+
+// mutexParam name 是否是 mutex 类型的参数
+func mutexParam(fn *ssa.Function, name string) bool {
+	for _, p := range fn.Params {
+		if p.Name() == name && isMutexType(p.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// stepInto 进入中间的结构体字段 m ，更新字段路径（与 instanceOf 一致），返回指向 m 所在结构体的指针类型
+func stepInto(m *types.Var, path *string) types.Type {
+	*path += "." + m.Name()
+	if _, ok := m.Type().Underlying().(*types.Pointer); ok {
+		*path += "*"
+		return m.Type()
+	}
+	return types.NewPointer(m.Type())
+}
+
+// entryState 函数入口的加锁状态：约定中调用前持有的 mutex
+func (la *lockAnalysis) entryState(fn *ssa.Function) lockSet {
+	s := lockSet{}
+	if c := contractOf(fn); c != nil {
+		for _, l := range c.requires {
+			s[l.key] = l.kind
+		}
+		for _, l := range c.releases {
+			s[l.key] = l.kind
+		}
+	}
+	return s
+}
+
+// checkContracts 检查调用约定了 requires releases 的函数时，调用处是否持有对应的 mutex
+func (analyzer *BaseAnalyzer) checkContracts() {
+	for _, node := range analyzer.cg.Nodes {
+		if !analyzer.inPkgs(node.Func) {
+			continue
+		}
+		for _, edge := range node.Out {
+			callee := edge.Callee.Func
+			c := contractOf(callee)
+			if c == nil || edge.Site == nil {
+				continue
+			}
+			pos := analyzer.prog.Fset.Position(edge.Site.Pos())
+			if !pos.IsValid() || nolint(getComment(pos)) {
+				continue
+			}
+			for _, locks := range [][]contractLock{c.requires, c.releases} {
+				for _, l := range locks {
+					k := keyFromCallee(l.key, edge.Site, callee)
+					if analyzer.locks.siteLocked(edge.Site, k, l.kind == lockWrite) {
+						continue
+					}
+					// 两个分析器得到相同的结果，输出时去重
+					analyzer.PrintsCall = append(analyzer.PrintsCall, fmt.Sprintf("[mutex check] %v:%v 调用 %v 需要持有 %v 。", pos.Filename, pos.Line, callee.Name(), l.expr))
+				}
+			}
+		}
+	}
+}
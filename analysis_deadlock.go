@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// 调用链查找的最大深度
+const maxLockPathDepth = 32
+
+// checkSelfDeadlock 检查持有 mutex 时，直接或通过调用的函数再次加锁
+func (analyzer *BaseAnalyzer) checkSelfDeadlock() {
+	for _, node := range analyzer.cg.Nodes {
+		if !analyzer.inPkgs(node.Func) {
+			continue
+		}
+		st := analyzer.locks.state(node.Func)
+		for _, block := range node.Func.Blocks {
+			for _, instr := range block.Instrs {
+				held := st.before[instr]
+				if len(held) == 0 {
+					continue
+				}
+				c, ok := instr.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				pos := analyzer.prog.Fset.Position(instr.Pos())
+				if nolint(getComment(pos)) {
+					continue
+				}
+				// 直接重复加锁；无法确定实例时不报
+				if k, op := lockOpOf(&c.Call); k.m != nil {
+					if (op == opLock || op == opRLock) && analyzer.mutexes[k.m] && k.knownInstance() && held[k] != lockNone {
+						path := printLockPath([]*callgraph.Node{node}, k.m, k.m)
+						analyzer.PrintsLock = append(analyzer.PrintsLock, fmt.Sprintf("[mutex check] %v:%v 持有 %v 时重复加锁：%v", pos.Filename, pos.Line, k.m.Name(), path))
+					}
+					continue
+				}
+				// 通过调用的函数重复加锁
+				for k := range held {
+					if !analyzer.mutexes[k.m] || !k.knownInstance() {
+						continue
+					}
+					for _, out := range node.Out {
+						if out.Site != c {
+							continue
+						}
+						paths := analyzer.findRelock(out.Callee, c, k, []*callgraph.Node{node}, map[*callgraph.Node]bool{})
+						if paths != nil {
+							path := printLockPath(paths, k.m, k.m)
+							analyzer.PrintsLock = append(analyzer.PrintsLock, fmt.Sprintf("[mutex check] %v:%v 持有 %v 时重复加锁：%v", pos.Filename, pos.Line, k.m.Name(), path))
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// findRelock 沿调用图查找对 k 加锁的函数，返回完整调用链。 k 是调用处 site 的 key ，
+// 沿调用链转换为被调函数中的 key ；实例没有传下去、或中途解锁过 k 的函数不再继续查找
+func (analyzer *BaseAnalyzer) findRelock(node *callgraph.Node, site ssa.CallInstruction, k lockKey, paths []*callgraph.Node, seen map[*callgraph.Node]bool) []*callgraph.Node {
+	if seen[node] || len(paths) >= maxLockPathDepth || !analyzer.inPkgs(node.Func) {
+		return nil
+	}
+	k, ok := keyIntoCallee(k, site, node.Func)
+	if !ok {
+		return nil
+	}
+	seen[node] = true
+	newPaths := append(paths[:len(paths):len(paths)], node)
+	for r := range analyzer.locks.summary(node.Func).releases {
+		if matchKey(r, k) {
+			return nil
+		}
+	}
+	for _, instr := range analyzer.locks.lockSitesOf(node.Func, k) {
+		if h, _ := lockOpOf(instr.(*ssa.Call).Common()); h.knownInstance() {
+			return newPaths
+		}
+	}
+	for _, out := range node.Out {
+		if _, ok := out.Site.(*ssa.Go); ok {
+			continue
+		}
+		if r := analyzer.findRelock(out.Callee, out.Site, k, newPaths, seen); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+// inPkgs 函数是否属于要检查的包
+func (analyzer *BaseAnalyzer) inPkgs(fn *ssa.Function) bool {
+	return fn != nil && fn.Pkg != nil && analyzer.pkgs[fn.Pkg.Pkg.Path()]
+}
+
+// printLockPath 输出调用链，并标注首个函数持有 held 、最后的函数对 locked 加锁
+func printLockPath(paths []*callgraph.Node, held, locked *types.Var) string {
+	s := printPaht(paths, false)
+	first := paths[0].Func.String()
+	return fmt.Sprintf("%v (holds %v)%v (locks %v)", first, held.Name(), s[len(first):], locked.Name())
+}
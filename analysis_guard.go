@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// guardedBy 解析要锁变量的注释 // guarded_by: mu (理由) ，行尾注释、文档注释都可以
+func guardedBy(fset *token.FileSet, cgs ...*ast.CommentGroup) (names []annotName) {
+	for _, cg := range cgs {
+		names = append(names, directiveNames(fset, cg, "guarded_by:")...)
+	}
+	return
+}
+
+// checklocks 兼容 gVisor checklocks 的注释 // +checklocks:mu // +checklocksread:mu
+func checklocks(fset *token.FileSet, cgs ...*ast.CommentGroup) (names []annotName) {
+	for _, cg := range cgs {
+		names = append(names, directiveNames(fset, cg, "+checklocks:")...)
+		names = append(names, directiveNames(fset, cg, "+checklocksread:")...)
+	}
+	return
+}
+
+// ignored 注释中有 nolint: mutex_check 或 +checklocksignore
+func ignored(cgs ...*ast.CommentGroup) bool {
+	for _, cg := range cgs {
+		if cg == nil {
+			continue
+		}
+		for _, l := range cg.List {
+			if nolint(l.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// valueGuardNames 全局变量上指定的 mutex ：注释 // guarded_by: mu ，以及 // +checklocks:mu 。 fset 为 nil 时不报错
+func valueGuardNames(fset *token.FileSet, genDecl *ast.GenDecl, spec *ast.ValueSpec) []annotName {
+	cgs := []*ast.CommentGroup{spec.Doc, spec.Comment}
+	if !genDecl.Lparen.IsValid() {
+		// var x int 的文档注释记录在 GenDecl 上
+		cgs = append(cgs, genDecl.Doc)
+	}
+	return append(guardedBy(fset, cgs...), checklocks(fset, cgs...)...)
+}
+
+// guardNames 字段上指定的 mutex ：注释 // guarded_by: mu // +checklocks:mu ，以及 tag guarded_by:"mu" 。 fset 为 nil 时不报错
+func guardNames(fset *token.FileSet, field *ast.Field) []annotName {
+	names := append(guardedBy(fset, field.Doc, field.Comment), checklocks(fset, field.Doc, field.Comment)...)
+	if field.Tag != nil {
+		if tag, err := strconv.Unquote(field.Tag.Value); err == nil {
+			if text := reflect.StructTag(tag).Get("guarded_by"); text != "" {
+				names = append(names, parseAnnotNames(fset, text, field.Tag.Pos())...)
+			}
+		}
+	}
+	return names
+}
+
+// lookupFieldGuard 在结构体类型 t 中查找 mutex 字段（包括嵌入结构体提升的）
+func lookupFieldGuard(t types.Type, pkg *types.Package, name string) *types.Var {
+	if t == nil {
+		return nil
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, pkg, name)
+	if v, ok := obj.(*types.Var); ok && v.IsField() && isMutexType(v.Type()) {
+		return v
+	}
+	return nil
+}
+
+// addGuard 记录变量 v 由 m 锁（ m 注释了 nolint 的不检查）。 mutex 注释与变量上的 guarded_by 指定的不同时，报错
+func (analyzer *BaseAnalyzer) addGuard(v, m *types.Var) {
+	if nolint(getComment(analyzer.prog.Fset.Position(m.Pos()))) {
+		return
+	}
+	if old, ok := analyzer.vars[v]; ok && (old != m || len(analyzer.paths[v]) > 0) {
+		pos := analyzer.prog.Fset.Position(v.Pos())
+		fmt.Printf("[mutex check] %v:%v %v 指定了不同的 mutex ：%v 、%v\n", pos.Filename, pos.Line, v.Name(), old.Name(), m.Name())
+		return
+	}
+	analyzer.vars[v] = m
+}
+
+// addGuardPath 记录嵌套路径上的字段 v 由 m 锁住。同一类型的字段可以经由不同结构体的路径访问，各自由不同的 mutex 锁住
+func (analyzer *BaseAnalyzer) addGuardPath(v, m *types.Var, fields []*types.Var) {
+	if nolint(getComment(analyzer.prog.Fset.Position(m.Pos()))) {
+		return
+	}
+	if old, ok := analyzer.vars[v]; ok && len(analyzer.paths[v]) == 0 {
+		pos := analyzer.prog.Fset.Position(v.Pos())
+		fmt.Printf("[mutex check] %v:%v %v 指定了不同的 mutex ：%v 、%v\n", pos.Filename, pos.Line, v.Name(), old.Name(), m.Name())
+		return
+	}
+	if _, ok := analyzer.vars[v]; !ok {
+		analyzer.vars[v] = m
+	}
+	analyzer.paths[v] = append(analyzer.paths[v], guardPath{mutex: m, fields: fields})
+}
+
+// guardPkg 解析 guarded_by 中 mutex 的包限定： pkg.name 的 pkg 可以是本包名，或者 file 中 import 的包名（ file 为 nil 时只认本包名）。
+// 返回 mutex 所在包的路径，以及去掉包限定后的各段
+func guardPkg(pkg *types.Package, file *types.Scope, name string) (string, []string) {
+	parts := strings.Split(name, ".")
+	if len(parts) == 1 || pkg.Scope().Lookup(parts[0]) != nil {
+		return pkg.Path(), parts
+	}
+	if file != nil {
+		if pkgName, ok := file.Lookup(parts[0]).(*types.PkgName); ok {
+			return pkgName.Imported().Path(), parts[1:]
+		}
+	}
+	if parts[0] == pkg.Name() {
+		return pkg.Path(), parts[1:]
+	}
+	return pkg.Path(), parts
+}
+
+// lookupGuard 在包 pkg 中查找 guarded_by 指定的 mutex ：全局变量（ registryMu ），或者全局变量的字段（ mgr.mu ）
+func lookupGuard(pkg *types.Package, parts []string) *types.Var {
+	v, ok := pkg.Scope().Lookup(parts[0]).(*types.Var)
+	if !ok {
+		return nil
+	}
+	for _, seg := range parts[1:] {
+		obj, _, _ := types.LookupFieldOrMethod(v.Type(), true, pkg, seg)
+		if v, ok = obj.(*types.Var); !ok {
+			return nil
+		}
+	}
+	if !isMutexType(v.Type()) {
+		return nil
+	}
+	return v
+}
+
+// resolveGuard 查找 guarded_by 指定的 mutex （可以在 import 的包中，如 reg.Mu ），返回 prog 中对应的变量
+func (analyzer *BaseAnalyzer) resolveGuard(pass *analysis.Pass, n annotName) *types.Var {
+	path, parts := guardPkg(pass.Pkg, fileScope(pass, n.pos), n.name)
+	pkg := analyzer.prog.ImportedPackage(path)
+	if pkg == nil {
+		return nil
+	}
+	return lookupGuard(pkg.Pkg, parts)
+}
+
+// fileScope 位置 pos 所在文件的作用域（ import 的包名）
+func fileScope(pass *analysis.Pass, pos token.Pos) *types.Scope {
+	for _, f := range pass.Files {
+		if f.Pos() <= pos && pos <= f.End() {
+			return pass.TypesInfo.Scopes[f]
+		}
+	}
+	return nil
+}
+
+// guardRefs 包内 guarded_by 引用到的 mutex （ pass 中的变量）。被引用的 mutex 可以不加注释
+func guardRefs(pass *analysis.Pass) map[*types.Var]bool {
+	refs := map[*types.Var]bool{}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.GenDecl:
+				for _, spec := range node.Specs {
+					if spec, ok := spec.(*ast.ValueSpec); ok {
+						for _, n := range valueGuardNames(nil, node, spec) {
+							if m := localGuard(pass, n.name); m != nil {
+								refs[m] = true
+							}
+						}
+					}
+				}
+			case *ast.StructType:
+				for _, field := range node.Fields.List {
+					for _, n := range guardNames(nil, field) {
+						if m := lookupFieldGuard(pass.TypesInfo.TypeOf(node), pass.Pkg, n.name); m != nil {
+							refs[m] = true
+						} else if m := localGuard(pass, n.name); m != nil {
+							refs[m] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	return refs
+}
+
+// localGuard 查找 guarded_by 指定的本包的 mutex （ pass 中的变量）
+func localGuard(pass *analysis.Pass, name string) *types.Var {
+	path, parts := guardPkg(pass.Pkg, nil, name)
+	if path != pass.Pkg.Path() {
+		return nil
+	}
+	return lookupGuard(pass.Pkg, parts)
+}
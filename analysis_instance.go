@@ -0,0 +1,286 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// lockKey 区分实例的 mutex ： a.mu 与 b.mu 是不同的 key
+type lockKey struct {
+	m    *types.Var
+	root ssa.Value // 实例的根（参数、全局变量、局部变量、闭包捕获的变量）；nil 表示全局 mutex ，或者无法确定实例
+	path string    // 从 root 到 mutex 所在结构体的字段路径
+}
+
+// matchKey 是否可能是同一个 mutex ；无法确定实例时，同一字段的任一实例都算
+func matchKey(a, b lockKey) bool {
+	if a.m != b.m {
+		return false
+	}
+	if a.root == nil || b.root == nil {
+		return true
+	}
+	return a.root == b.root && a.path == b.path
+}
+
+// knownInstance 是否确定是哪个实例（全局 mutex 总是确定的）
+func (k lockKey) knownInstance() bool {
+	return k.root != nil || !k.m.IsField()
+}
+
+// keyFor mutex m 在结构体实例 base （指向结构体的指针）上的 key
+func keyFor(m *types.Var, base ssa.Value) lockKey {
+	if !m.IsField() || base == nil {
+		return lockKey{m: m}
+	}
+	base = ownerOf(m, base)
+	root, path := instanceOf(base)
+	return lockKey{m: m, root: root, path: path}
+}
+
+// guardPath 嵌套路径上要锁的字段：锁它的 mutex ，以及从 mutex 所在结构体起经过的字段
+type guardPath struct {
+	mutex  *types.Var
+	fields []*types.Var
+}
+
+// guardKey 访问要锁的字段 v （ x 是字段所在的结构体实例）时，需要持有的 mutex 的 key 。
+// v 是嵌套路径上的字段时（如 s.stats.hits s.items[i].state ），沿路径找到 mutex 所在的结构体实例；
+// 不是经由这些路径访问的（如单独的 Stats 变量），不需要加锁，返回 false
+func (analyzer *BaseAnalyzer) guardKey(v *types.Var, x ssa.Value) (lockKey, bool) {
+	paths := analyzer.paths[v]
+	if len(paths) == 0 {
+		return keyFor(analyzer.vars[v], x), true
+	}
+	for _, path := range paths {
+		base, ok := x, true
+		for i := len(path.fields) - 1; i >= 0 && ok; i-- {
+			base, ok = parentOf(base, path.fields[i])
+		}
+		if ok {
+			return keyFor(path.mutex, base), true
+		}
+	}
+	return lockKey{}, false
+}
+
+// parentOf 从 x 向外找到字段 field 的访问（跳过解引用、取元素、嵌入结构体），返回其所在的结构体实例
+func parentOf(x ssa.Value, field *types.Var) (ssa.Value, bool) {
+	for {
+		switch v := x.(type) {
+		case *ssa.UnOp:
+			if v.Op != token.MUL {
+				return nil, false
+			}
+			x = v.X
+		case *ssa.IndexAddr:
+			x = v.X
+		case *ssa.Index:
+			x = v.X
+		case *ssa.Lookup:
+			x = v.X
+		case *ssa.FieldAddr:
+			f := structFieldOf(v)
+			if f == field {
+				return v.X, true
+			}
+			if f == nil || !f.Anonymous() {
+				return nil, false
+			}
+			x = v.X
+		default:
+			return nil, false
+		}
+	}
+}
+
+// ownerOf 字段经嵌入结构体提升时（如 o.a 即 o.Inner.a ）， mutex m 所在的外层结构体实例
+func ownerOf(m *types.Var, base ssa.Value) ssa.Value {
+	for !hasField(base.Type(), m) {
+		x := base
+		if load, ok := x.(*ssa.UnOp); ok && load.Op == token.MUL {
+			// 嵌入的是指针
+			x = load.X
+		}
+		fa, ok := x.(*ssa.FieldAddr)
+		if !ok {
+			break
+		}
+		if field := structFieldOf(fa); field == nil || !field.Anonymous() {
+			break
+		}
+		base = fa.X
+	}
+	return base
+}
+
+// hasField 指针 t 指向的结构体是否直接包含字段 m
+func hasField(t types.Type, m *types.Var) bool {
+	p, ok := t.Underlying().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	st, ok := p.Elem().Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// isPromoted 字段访问是否经过嵌入结构体（提升的字段）
+func isPromoted(fa *ssa.FieldAddr) bool {
+	x := fa.X
+	if load, ok := x.(*ssa.UnOp); ok && load.Op == token.MUL {
+		x = load.X
+	}
+	if embed, ok := x.(*ssa.FieldAddr); ok {
+		if field := structFieldOf(embed); field != nil && field.Anonymous() {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorOf 字段访问的选择器路径（如 o.Inner.a ），用于报错
+func selectorOf(v ssa.Value) string {
+	switch v := v.(type) {
+	case *ssa.Parameter:
+		return v.Name()
+	case *ssa.FreeVar:
+		return v.Name()
+	case *ssa.Global:
+		return v.Name()
+	case *ssa.Alloc:
+		if v.Comment != "complit" && v.Comment != "new" {
+			return v.Comment
+		}
+	case *ssa.FieldAddr:
+		if field := structFieldOf(v); field != nil {
+			if s := selectorOf(v.X); s != "" {
+				return s + "." + field.Name()
+			}
+			return field.Name()
+		}
+	case *ssa.UnOp:
+		if v.Op == token.MUL {
+			return selectorOf(v.X)
+		}
+	}
+	return ""
+}
+
+// instanceOf 计算结构体实例的根及字段路径；无法确定时（如函数返回值、map 元素）返回 nil
+func instanceOf(v ssa.Value) (ssa.Value, string) {
+	switch v := v.(type) {
+	case *ssa.Parameter, *ssa.Global, *ssa.Alloc, *ssa.FreeVar:
+		return v, ""
+	case *ssa.FieldAddr:
+		root, path := instanceOf(v.X)
+		field := structFieldOf(v)
+		if root == nil || field == nil {
+			return nil, ""
+		}
+		return root, path + "." + field.Name()
+	case *ssa.UnOp:
+		if v.Op == token.MUL {
+			root, path := instanceOf(v.X)
+			if root == nil {
+				return nil, ""
+			}
+			return root, path + "*"
+		}
+	case *ssa.ChangeType:
+		return instanceOf(v.X)
+	}
+	return nil, ""
+}
+
+// callArg 被调函数第 i 个参数在调用处对应的值
+func callArg(c *ssa.CallCommon, i int) ssa.Value {
+	if c.IsInvoke() {
+		if i == 0 {
+			return c.Value
+		}
+		i--
+	}
+	if i < len(c.Args) {
+		return c.Args[i]
+	}
+	return nil
+}
+
+// keyFromCallee 把被调函数中的 key （以其参数、捕获变量为根）转换为调用处的 key
+func keyFromCallee(k lockKey, site ssa.CallInstruction, callee *ssa.Function) lockKey {
+	switch root := k.root.(type) {
+	case nil, *ssa.Global:
+		return k
+	case *ssa.Parameter:
+		for i, p := range callee.Params {
+			if p != root {
+				continue
+			}
+			if arg := callArg(site.Common(), i); arg != nil {
+				if r, path := instanceOf(arg); r != nil {
+					return lockKey{m: k.m, root: r, path: path + k.path}
+				}
+			}
+		}
+	case *ssa.FreeVar:
+		if mc, ok := site.Common().Value.(*ssa.MakeClosure); ok {
+			for i, fv := range callee.FreeVars {
+				if fv == root && i < len(mc.Bindings) {
+					if r, path := instanceOf(mc.Bindings[i]); r != nil {
+						return lockKey{m: k.m, root: r, path: path + k.path}
+					}
+				}
+			}
+		}
+	}
+	return lockKey{m: k.m}
+}
+
+// keyIntoCallee 把调用处的 key 转换为被调函数中的 key ；实例没有传给被调函数时，返回 false
+func keyIntoCallee(k lockKey, site ssa.CallInstruction, callee *ssa.Function) (lockKey, bool) {
+	if k.root == nil {
+		return k, true
+	}
+	if _, ok := k.root.(*ssa.Global); ok {
+		return k, true
+	}
+	for i, p := range callee.Params {
+		arg := callArg(site.Common(), i)
+		if arg == nil {
+			continue
+		}
+		if r, path := instanceOf(arg); r == k.root && pathHasPrefix(k.path, path) {
+			return lockKey{m: k.m, root: p, path: k.path[len(path):]}, true
+		}
+	}
+	if mc, ok := site.Common().Value.(*ssa.MakeClosure); ok {
+		for i, fv := range callee.FreeVars {
+			if i >= len(mc.Bindings) {
+				break
+			}
+			if r, path := instanceOf(mc.Bindings[i]); r == k.root && pathHasPrefix(k.path, path) {
+				return lockKey{m: k.m, root: fv, path: k.path[len(path):]}, true
+			}
+		}
+	}
+	return lockKey{}, false
+}
+
+// pathHasPrefix 字段路径 path 是否以 prefix 开头（按 .field 与 * 分段比较）
+func pathHasPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '.' || path[len(prefix)] == '*'
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// checkLockLeak 检查加锁后，有路径返回时仍持有 mutex （没有 Unlock ，也没有 defer Unlock ）。
+// 所有路径返回时都持有的函数，视为有意加锁（如 lockAll），不报错
+func (analyzer *BaseAnalyzer) checkLockLeak() {
+	for _, node := range analyzer.cg.Nodes {
+		if !analyzer.inPkgs(node.Func) {
+			continue
+		}
+		fn := node.Func
+		sum := analyzer.locks.summary(fn)
+		may := analyzer.locks.leakState(fn)
+		entry := analyzer.locks.entryState(fn) // 约定中调用者持有的，不是本函数加锁
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				if _, ok := instr.(*ssa.Return); !ok {
+					continue
+				}
+				for k := range may[instr] {
+					if !analyzer.mutexes[k.m] || sum.acquires.kindOf(k) != lockNone || entry.kindOf(k) != lockNone {
+						continue
+					}
+					pos := analyzer.prog.Fset.Position(instr.Pos())
+					if !pos.IsValid() && fn.Syntax() != nil {
+						// 隐式的 return ，使用函数结束的位置
+						pos = analyzer.prog.Fset.Position(fn.Syntax().End())
+					}
+					if nolint(getComment(pos)) {
+						continue
+					}
+					analyzer.PrintsLock = append(analyzer.PrintsLock, fmt.Sprintf("[mutex check] %v:%v 返回时可能仍持有 %v ，没有 Unlock （加锁位置 %v）。", pos.Filename, pos.Line, k.m.Name(), analyzer.lockSitesDesc(fn, k)))
+				}
+			}
+		}
+	}
+}
+
+// leakState 可能持有、且还没有注册 defer 解锁的 mutex 。
+// defer 只对注册之后的返回生效，因此按路径计算：执行到 defer 时，去掉它会解锁的 mutex
+func (la *lockAnalysis) leakState(fn *ssa.Function) map[ssa.Instruction]lockSet {
+	return solve(fn, la.entryState(fn), lockSet{}, lockSet.join, func(instr ssa.Instruction, s lockSet) lockSet {
+		if d, ok := instr.(*ssa.Defer); ok {
+			for _, k := range la.releasedBy(d) {
+				s = s.without(k)
+			}
+			return s
+		}
+		return la.transfer(instr, s)
+	})
+}
+
+// lockSitesDesc 输出函数内对 k 加锁的位置
+func (analyzer *BaseAnalyzer) lockSitesDesc(fn *ssa.Function, k lockKey) string {
+	var s string
+	for _, instr := range analyzer.locks.lockSitesOf(fn, k) {
+		pos := analyzer.prog.Fset.Position(instr.Pos())
+		if s != "" {
+			s += ", "
+		}
+		s += fmt.Sprintf("%v:%v", filepath.Base(pos.Filename), pos.Line)
+	}
+	return s
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// lockKind 持有 mutex 的方式
+type lockKind int
+
+const (
+	lockNone  lockKind = iota
+	lockRead           // RLock
+	lockWrite          // Lock
+)
+
+// lockSet 某一程序点上，确定持有的 mutex
+type lockSet map[lockKey]lockKind
+
+func (s lockSet) clone() lockSet {
+	n := make(lockSet, len(s))
+	for k, v := range s {
+		n[k] = v
+	}
+	return n
+}
+
+// meet 多条路径汇合时，只有所有路径都持有的 mutex 才算持有，并取较弱的持有方式
+func (s lockSet) meet(o lockSet) lockSet {
+	n := lockSet{}
+	for k, v := range s {
+		if ov := o[k]; ov != lockNone {
+			if ov < v {
+				v = ov
+			}
+			n[k] = v
+		}
+	}
+	return n
+}
+
+// join 多条路径汇合时，任一路径持有的 mutex 都算可能持有，并取较强的持有方式
+func (s lockSet) join(o lockSet) lockSet {
+	n := s.clone()
+	for k, v := range o {
+		if v > n[k] {
+			n[k] = v
+		}
+	}
+	return n
+}
+
+// kindOf 查询 key 的持有方式；实例无法确定时，同一字段的任一实例都算
+func (s lockSet) kindOf(k lockKey) lockKind {
+	if v, ok := s[k]; ok {
+		return v
+	}
+	var kind lockKind
+	for h, v := range s {
+		if matchKey(h, k) && v > kind {
+			kind = v
+		}
+	}
+	return kind
+}
+
+// hasMutex 是否持有 m 的某个实例
+func (s lockSet) hasMutex(m *types.Var) bool {
+	for k := range s {
+		if k.m == m {
+			return true
+		}
+	}
+	return false
+}
+
+// without 去掉所有可能是 k 的 mutex
+func (s lockSet) without(k lockKey) lockSet {
+	var n lockSet
+	for h := range s {
+		if matchKey(h, k) {
+			if n == nil {
+				n = s.clone()
+			}
+			delete(n, h)
+		}
+	}
+	if n == nil {
+		return s
+	}
+	return n
+}
+
+func (s lockSet) equal(o lockSet) bool {
+	if len(s) != len(o) {
+		return false
+	}
+	for k, v := range s {
+		if o[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+type lockOp int
+
+const (
+	opNone lockOp = iota
+	opLock
+	opUnlock
+	opRLock
+	opRUnlock
+)
+
+// funcLockState 函数内每条指令执行前的加锁状态
+type funcLockState struct {
+	before map[ssa.Instruction]lockSet // 确定持有
+	last   map[ssa.Instruction]lastOps // 最近一次加锁、解锁操作，按需计算
+}
+
+// lockAnalysis 基于 SSA 基本块的数据流分析，计算每条指令处确定持有的 mutex
+type lockAnalysis struct {
+	prog      *ssa.Program
+	cg        *callgraph.Graph
+	states    map[*ssa.Function]*funcLockState
+	summaries map[*ssa.Function]*funcSummary
+	computing map[*ssa.Function]bool
+}
+
+func newLockAnalysis(prog *ssa.Program, cg *callgraph.Graph) *lockAnalysis {
+	return &lockAnalysis{
+		prog:      prog,
+		cg:        cg,
+		states:    map[*ssa.Function]*funcLockState{},
+		summaries: map[*ssa.Function]*funcSummary{},
+		computing: map[*ssa.Function]bool{},
+	}
+}
+
+func (la *lockAnalysis) state(fn *ssa.Function) *funcLockState {
+	if st, ok := la.states[fn]; ok {
+		return st
+	}
+	st := la.compute(fn)
+	la.states[fn] = st
+	return st
+}
+
+// lockedKind 指令 instr 执行前，确定持有 mutex k 的方式
+func (la *lockAnalysis) lockedKind(instr ssa.Instruction, k lockKey) lockKind {
+	if instr.Parent() == nil {
+		return lockNone
+	}
+	return la.state(instr.Parent()).before[instr].kindOf(k)
+}
+
+// mayHold 函数内是否有持有 mutex m 的地方
+func (la *lockAnalysis) mayHold(fn *ssa.Function, m *types.Var) bool {
+	for _, s := range la.state(fn).before {
+		if s.hasMutex(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockSites 函数内对 mutex m 加锁（Lock 或 RLock）的指令
+func (la *lockAnalysis) lockSites(fn *ssa.Function, m *types.Var) (instrs []ssa.Instruction) {
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			if c, ok := instr.(*ssa.Call); ok {
+				if k, op := lockOpOf(&c.Call); k.m == m && (op == opLock || op == opRLock) {
+					instrs = append(instrs, instr)
+				}
+			}
+		}
+	}
+	return
+}
+
+// lockSitesOf 函数内对 k 可能是同一实例的 mutex 加锁的指令
+func (la *lockAnalysis) lockSitesOf(fn *ssa.Function, k lockKey) (instrs []ssa.Instruction) {
+	for _, instr := range la.lockSites(fn, k.m) {
+		if h, _ := lockOpOf(instr.(*ssa.Call).Common()); matchKey(h, k) {
+			instrs = append(instrs, instr)
+		}
+	}
+	return
+}
+
+// isLocked 指令 instr 执行前，是否确定持有 mutex k ； write 为 true 时，要求持有 Lock
+func (la *lockAnalysis) isLocked(instr ssa.Instruction, k lockKey, write bool) bool {
+	kind := la.lockedKind(instr, k)
+	if write {
+		return kind == lockWrite
+	}
+	return kind != lockNone
+}
+
+// siteLocked 调用 site 的被调函数执行时，是否确定持有 mutex k
+func (la *lockAnalysis) siteLocked(site ssa.CallInstruction, k lockKey, write bool) bool {
+	switch site := site.(type) {
+	case *ssa.Go:
+		// 协程中执行，不持有锁
+		return false
+	case *ssa.Defer:
+		// 函数返回时执行
+		return la.isLockedAtDefer(site, k, write)
+	}
+	return la.isLocked(site, k, write)
+}
+
+// isLockedAtDefer defer 调用执行时（函数返回时），是否确定持有 mutex k 。
+// 只检查注册了 d 的路径上的返回；同一路径上之后注册的 defer 会先执行，其中解锁了 k 的，视为没有持有
+func (la *lockAnalysis) isLockedAtDefer(d *ssa.Defer, k lockKey, write bool) bool {
+	fn := d.Parent()
+	before := la.state(fn).before
+	after := solve(fn, deferPath{}, deferPath{}, deferPath.merge, func(instr ssa.Instruction, s deferPath) deferPath {
+		switch {
+		case instr == d:
+			n := deferPath{registered: true, held: before[d].clone(), released: map[lockKey]bool{}}
+			for r := range s.released {
+				// 循环中再次注册时，保留上一次之后注册的 defer
+				n.released[r] = true
+			}
+			return n
+		case !s.registered:
+			return s
+		}
+		if other, ok := instr.(*ssa.Defer); ok {
+			n := s.clone()
+			for _, r := range la.releasedBy(other) {
+				n.released[r] = true
+			}
+			return n
+		}
+		if held := la.transfer(instr, s.held); !held.equal(s.held) {
+			n := s.clone()
+			n.held = held
+			return n
+		}
+		return s
+	})
+	var returned bool
+	for _, block := range fn.Blocks {
+		if block == fn.Recover {
+			// panic 恢复后的返回，defer 已经执行过
+			continue
+		}
+		for _, instr := range block.Instrs {
+			ret, ok := instr.(*ssa.Return)
+			if !ok {
+				continue
+			}
+			s, ok := after[ret]
+			if !ok || !s.registered {
+				continue
+			}
+			returned = true
+			kind := s.held.kindOf(k)
+			if kind == lockNone || (write && kind != lockWrite) {
+				return false
+			}
+			for r := range s.released {
+				if matchKey(r, k) {
+					return false
+				}
+			}
+		}
+	}
+	return returned
+}
+
+// deferPath 注册了某个 defer 的路径上：确定持有的 mutex ，以及之后注册的 defer 会解锁的 mutex
+type deferPath struct {
+	registered bool
+	held       lockSet
+	released   map[lockKey]bool
+}
+
+func (s deferPath) clone() deferPath {
+	n := deferPath{registered: s.registered, held: s.held.clone(), released: make(map[lockKey]bool, len(s.released))}
+	for k := range s.released {
+		n.released[k] = true
+	}
+	return n
+}
+
+func (s deferPath) equal(o deferPath) bool {
+	if s.registered != o.registered || !s.held.equal(o.held) || len(s.released) != len(o.released) {
+		return false
+	}
+	for k := range s.released {
+		if !o.released[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// merge 没有注册的路径不参与合并；都注册了的，持有取交集，解锁取并集
+func (s deferPath) merge(o deferPath) deferPath {
+	switch {
+	case !o.registered:
+		return s
+	case !s.registered:
+		return o.clone()
+	}
+	n := s.clone()
+	n.held = s.held.meet(o.held)
+	for k := range o.released {
+		n.released[k] = true
+	}
+	return n
+}
+
+func (la *lockAnalysis) compute(fn *ssa.Function) *funcLockState {
+	return &funcLockState{before: solve(fn, la.entryState(fn), lockSet{}, lockSet.meet, la.transfer)}
+}
+
+// flowState 数据流分析的状态，有变化时返回新的状态，不修改原状态
+type flowState[S any] interface {
+	clone() S
+	equal(S) bool
+}
+
+// solve 从入口状态 entry 开始（ recover 块从 recovered 开始），在基本块上迭代到不动点，
+// merge 决定多条路径汇合时如何合并，transfer 计算指令执行后的状态
+func solve[S flowState[S]](fn *ssa.Function, entry, recovered S, merge func(S, S) S, transfer func(ssa.Instruction, S) S) map[ssa.Instruction]S {
+	before := map[ssa.Instruction]S{}
+	if len(fn.Blocks) == 0 {
+		return before
+	}
+	in := make([]S, len(fn.Blocks))
+	reached := make([]bool, len(fn.Blocks))
+	in[0], reached[0] = entry, true
+	work := []*ssa.BasicBlock{fn.Blocks[0]}
+	if fn.Recover != nil {
+		in[fn.Recover.Index], reached[fn.Recover.Index] = recovered, true
+		work = append(work, fn.Recover)
+	}
+	for len(work) > 0 {
+		b := work[0]
+		work = work[1:]
+		out := in[b.Index]
+		for _, instr := range b.Instrs {
+			out = transfer(instr, out)
+		}
+		for _, succ := range b.Succs {
+			if !reached[succ.Index] {
+				in[succ.Index], reached[succ.Index] = out.clone(), true
+				work = append(work, succ)
+				continue
+			}
+			old := in[succ.Index]
+			next := merge(old, out)
+			if !next.equal(old) {
+				in[succ.Index] = next
+				work = append(work, succ)
+			}
+		}
+	}
+	for _, b := range fn.Blocks {
+		if !reached[b.Index] {
+			continue
+		}
+		cur := in[b.Index]
+		for _, instr := range b.Instrs {
+			before[instr] = cur
+			cur = transfer(instr, cur)
+		}
+	}
+	return before
+}
+
+// transfer 计算指令执行后的加锁状态；有变化时返回新的集合，不修改 s
+func (la *lockAnalysis) transfer(instr ssa.Instruction, s lockSet) lockSet {
+	c, ok := instr.(*ssa.Call)
+	if !ok {
+		return s
+	}
+	k, op := lockOpOf(&c.Call)
+	if k.m == nil {
+		if op == opNone {
+			// 普通函数调用，使用被调函数的摘要
+			return la.applySummary(c, s)
+		}
+		return s
+	}
+	switch op {
+	case opLock, opRLock:
+		kind := lockWrite
+		if op == opRLock {
+			kind = lockRead
+		}
+		if s[k] != kind {
+			s = s.clone()
+			s[k] = kind
+		}
+	case opUnlock, opRUnlock:
+		s = s.without(k)
+	}
+	return s
+}
+
+// lockOpOf 判断调用是否是锁类型（默认 sync.Mutex sync.RWMutex sync.Locker ，可配置）的加锁、解锁，并返回对应的 mutex
+func lockOpOf(c *ssa.CallCommon) (lockKey, lockOp) {
+	if c.IsInvoke() {
+		// 通过接口调用，如 sync.Locker
+		if lt := lockTypeOf(c.Value.Type()); lt != nil {
+			if op := lt.opOf(c.Method.Name()); op != opNone {
+				return mutexOfValue(c.Value), op
+			}
+		}
+		return lockKey{}, opNone
+	}
+	fn := c.StaticCallee()
+	if fn == nil || fn.Signature.Recv() == nil || len(c.Args) == 0 {
+		return lockKey{}, opNone
+	}
+	lt := lockTypeOf(fn.Signature.Recv().Type())
+	if lt == nil {
+		return lockKey{}, opNone
+	}
+	op := lt.opOf(fn.Name())
+	if op == opNone {
+		return lockKey{}, opNone
+	}
+	return mutexOfValue(c.Args[0]), op
+}
+
+// mutexOfValue 根据 Lock/Unlock 的接收者，找到对应的全局变量或结构体字段（及其所在的实例）
+func mutexOfValue(v ssa.Value) lockKey {
+	switch v := v.(type) {
+	case *ssa.Global:
+		if obj, ok := v.Object().(*types.Var); ok {
+			return lockKey{m: obj}
+		}
+	case *ssa.FieldAddr:
+		// 包装类型（如 type Mu struct{ sync.Mutex }）中的 mutex ，取最外层声明的 mutex
+		if outer := outerMutexOf(v.X); outer != nil {
+			return mutexOfValue(outer)
+		}
+		if field := structFieldOf(v); field != nil {
+			return keyFor(field, v.X)
+		}
+	case *ssa.UnOp:
+		if v.Op == token.MUL {
+			return mutexOfValue(v.X)
+		}
+	case *ssa.MakeInterface:
+		return mutexOfValue(v.X)
+	case *ssa.ChangeInterface:
+		return mutexOfValue(v.X)
+	}
+	return lockKey{}
+}
+
+// outerMutexOf x 是指向结构体的指针；结构体本身是 mutex 类型的全局变量或字段时，返回它的地址
+func outerMutexOf(x ssa.Value) ssa.Value {
+	if load, ok := x.(*ssa.UnOp); ok && load.Op == token.MUL {
+		// 指针类型的全局变量、字段，如 var m = newMu()
+		x = load.X
+	}
+	switch x := x.(type) {
+	case *ssa.Global, *ssa.FieldAddr:
+		if p, ok := x.Type().Underlying().(*types.Pointer); ok && isMutexType(p.Elem()) {
+			return x
+		}
+	}
+	return nil
+}
+
+func structFieldOf(fieldAddr *ssa.FieldAddr) *types.Var {
+	if pointerType, ok := fieldAddr.X.Type().Underlying().(*types.Pointer); ok {
+		if structType, ok := pointerType.Elem().Underlying().(*types.Struct); ok {
+			return structType.Field(fieldAddr.Field)
+		}
+	}
+	return nil
+}
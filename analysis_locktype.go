@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// lockType 锁类型，及其加锁、解锁方法名；没有读锁的，读锁方法名为空
+type lockType struct {
+	pkg, name      string
+	lock, unlock   string
+	rlock, runlock string
+}
+
+// 默认支持的锁类型，可以通过 --locktypes 增加
+var lockTypes = []*lockType{
+	{pkg: "sync", name: "Mutex", lock: "Lock", unlock: "Unlock"},
+	{pkg: "sync", name: "RWMutex", lock: "Lock", unlock: "Unlock", rlock: "RLock", runlock: "RUnlock"},
+	{pkg: "sync", name: "Locker", lock: "Lock", unlock: "Unlock"},
+}
+
+var lockTypeCache = map[types.Type]*lockType{}
+
+// parseLockTypes 解析 --locktypes ，格式： 包路径.类型:加锁,解锁[,读加锁,读解锁] ，多个用 ; 分隔。
+// 如： github.com/sasha-s/go-deadlock.RWMutex:Lock,Unlock,RLock,RUnlock;example.com/spin.Lock:Acquire,Release
+func parseLockTypes(s string) error {
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		parts[0] = strings.TrimSpace(parts[0])
+		dot := strings.LastIndex(parts[0], ".")
+		if len(parts) != 2 || dot <= 0 || dot == len(parts[0])-1 {
+			return fmt.Errorf("locktypes 格式错误：%v ，应为 包路径.类型:加锁,解锁[,读加锁,读解锁]", item)
+		}
+		methods := strings.Split(parts[1], ",")
+		for i := range methods {
+			methods[i] = strings.TrimSpace(methods[i])
+			if methods[i] == "" {
+				// 空的方法名（如 a.B: , ），与个数不对一样报错
+				methods = nil
+				break
+			}
+		}
+		if len(methods) != 2 && len(methods) != 4 {
+			return fmt.Errorf("locktypes 格式错误：%v ，方法名应为 2 个或 4 个", item)
+		}
+		lt := &lockType{pkg: parts[0][:dot], name: parts[0][dot+1:], lock: methods[0], unlock: methods[1]}
+		if len(methods) == 4 {
+			lt.rlock, lt.runlock = methods[2], methods[3]
+		}
+		lockTypes = append(lockTypes, lt)
+	}
+	return nil
+}
+
+// lockTypeOf 类型 t （或其指针）是否是锁类型，包括类型别名
+func lockTypeOf(t types.Type) *lockType {
+	if lt, ok := lockTypeCache[t]; ok {
+		return lt
+	}
+	lt := findLockType(t)
+	lockTypeCache[t] = lt
+	return lt
+}
+
+func findLockType(t types.Type) *lockType {
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return matchLockType(named)
+	}
+	// 类型别名：加锁方法直接定义在锁类型上
+	mset := types.NewMethodSet(types.NewPointer(t))
+	for _, lt := range lockTypes {
+		sel := mset.Lookup(nil, lt.lock)
+		if sel == nil || len(sel.Index()) != 1 {
+			continue
+		}
+		recv := sel.Obj().Type().(*types.Signature).Recv()
+		if recv == nil {
+			continue
+		}
+		rt := recv.Type()
+		if p, ok := rt.(*types.Pointer); ok {
+			rt = p.Elem()
+		}
+		if named, ok := rt.(*types.Named); ok && matchLockType(named) == lt {
+			return lt
+		}
+	}
+	return nil
+}
+
+func matchLockType(named *types.Named) *lockType {
+	obj := named.Obj()
+	if obj.Pkg() == nil {
+		return nil
+	}
+	for _, lt := range lockTypes {
+		if obj.Pkg().Path() == lt.pkg && obj.Name() == lt.name {
+			return lt
+		}
+	}
+	return nil
+}
+
+// opOf 方法名对应的加锁、解锁操作
+func (lt *lockType) opOf(name string) lockOp {
+	switch name {
+	case "":
+		return opNone
+	case lt.lock:
+		return opLock
+	case lt.unlock:
+		return opUnlock
+	case lt.rlock:
+		return opRLock
+	case lt.runlock:
+		return opRUnlock
+	}
+	return opNone
+}
+
+// promotedLockType 类型 t 的方法集中，通过嵌入得到的锁类型的加锁方法
+func promotedLockType(t types.Type) *lockType {
+	mset := types.NewMethodSet(types.NewPointer(t))
+	for _, lt := range lockTypes {
+		sel := mset.Lookup(nil, lt.lock)
+		if sel == nil {
+			continue
+		}
+		if recv := sel.Obj().Type().(*types.Signature).Recv(); recv != nil && lockTypeOf(recv.Type()) == lt {
+			return lt
+		}
+	}
+	return nil
+}
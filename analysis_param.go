@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// paramUse 被调函数中，对传入的要锁变量（形参）的一次使用
+type paramUse struct {
+	param *ssa.Parameter
+	instr ssa.Instruction
+	write bool
+}
+
+// checkParams 检查加锁时取出的要锁变量（map slice 指针），解锁后传给其他函数：
+// 被调函数（沿调用链）对形参的使用，也要持有锁。调用处、使用处都报错
+func (analyzer *BaseAnalyzer) checkParams() {
+	for _, node := range analyzer.cg.Nodes {
+		if !analyzer.inPkgs(node.Func) {
+			continue
+		}
+		for _, block := range node.Func.Blocks {
+			for _, instr := range block.Instrs {
+				site, ok := instr.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				sitePos := analyzer.prog.Fset.Position(site.Pos())
+				if nolint(getComment(sitePos)) {
+					continue
+				}
+				for _, callee := range analyzer.locks.callees(site) {
+					if !analyzer.inPkgs(callee) {
+						continue
+					}
+					for i, p := range callee.Params {
+						myvar, key, load := analyzer.guardedSource(callArg(site.Common(), i))
+						if myvar == nil {
+							continue
+						}
+						// 取值时没有加锁的，在变量访问检查中报错
+						if !analyzer.locks.isLocked(load, key, false) {
+							continue
+						}
+						if analyzer.locks.siteLocked(site, key, false) {
+							continue
+						}
+						calleeKey, ok := keyIntoCallee(key, site, callee)
+						if !ok {
+							calleeKey = lockKey{m: key.m}
+						}
+						uses := analyzer.paramUses(p, p, calleeKey, map[*ssa.Parameter]bool{})
+						mymutex := key.m
+						for _, use := range uses {
+							usePos := analyzer.prog.Fset.Position(use.instr.Pos())
+							analyzer.PrintsCall = append(analyzer.PrintsCall, fmt.Sprintf("[mutex check] %v:%v 传入的 %v 需要持有 %v 才能使用，被调函数中没有加锁（使用位置 %v:%v）。", sitePos.Filename, sitePos.Line, myvar.Name(), mymutex.Name(), usePos.Filename, usePos.Line))
+							analyzer.PrintsCall = append(analyzer.PrintsCall, fmt.Sprintf("[mutex check] %v:%v 参数 %v 来自 %v ，使用时没有持有 %v （调用位置 %v:%v）。", usePos.Filename, usePos.Line, use.param.Name(), myvar.Name(), mymutex.Name(), sitePos.Filename, sitePos.Line))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// guardedSource 判断实参是否取自要锁的变量（map slice 指针），返回变量、对应的 mutex 以及取值指令
+func (analyzer *BaseAnalyzer) guardedSource(v ssa.Value) (*types.Var, lockKey, ssa.Instruction) {
+	switch v := v.(type) {
+	case *ssa.UnOp:
+		if v.Op != token.MUL || !isReferenceType(v.Type()) {
+			break
+		}
+		if myvar, key := analyzer.guardedAddr(v.X); myvar != nil {
+			return myvar, key, v
+		}
+	case *ssa.Slice:
+		// 数组切片，直接引用变量的地址
+		if myvar, key := analyzer.guardedAddr(v.X); myvar != nil {
+			return myvar, key, v
+		}
+		return analyzer.guardedSource(v.X)
+	case *ssa.ChangeType:
+		return analyzer.guardedSource(v.X)
+	}
+	return nil, lockKey{}, nil
+}
+
+// guardedAddr 判断地址是否是要锁的全局变量或结构体字段
+func (analyzer *BaseAnalyzer) guardedAddr(addr ssa.Value) (*types.Var, lockKey) {
+	switch addr := addr.(type) {
+	case *ssa.Global:
+		if obj, ok := addr.Object().(*types.Var); ok {
+			if m, ok := analyzer.vars[obj]; ok {
+				return obj, lockKey{m: m}
+			}
+		}
+	case *ssa.FieldAddr:
+		if field := structFieldOf(addr); field != nil {
+			if _, ok := analyzer.vars[field]; ok {
+				if key, ok := analyzer.guardKey(field, addr.X); ok {
+					return field, key
+				}
+			}
+		}
+	}
+	return nil, lockKey{}
+}
+
+func isReferenceType(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Map, *types.Slice, *types.Pointer:
+		return true
+	}
+	return false
+}
+
+// paramUses 找到函数内（以及继续传给的函数内）没有持有 key 时对形参 p （或由 p 得到的 v ）的使用
+func (analyzer *BaseAnalyzer) paramUses(p *ssa.Parameter, v ssa.Value, key lockKey, seen map[*ssa.Parameter]bool) (uses []paramUse) {
+	if v == p {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+	}
+	refs := v.Referrers()
+	if refs == nil {
+		return
+	}
+	for _, r := range *refs {
+		switch r := r.(type) {
+		case *ssa.DebugRef:
+			continue
+		case *ssa.ChangeType:
+			uses = append(uses, analyzer.paramUses(p, r, key, seen)...)
+			continue
+		case *ssa.Slice:
+			uses = append(uses, analyzer.paramUses(p, r, key, seen)...)
+			continue
+		case ssa.CallInstruction:
+			if uses2, ok := analyzer.paramCallUses(r, v, key, seen); ok {
+				uses = append(uses, uses2...)
+				continue
+			}
+		}
+		// 没有位置的指令（如 range 生成的 len），由同一语句的其他指令报错
+		if pos := analyzer.prog.Fset.Position(r.Pos()); !pos.IsValid() || nolint(getComment(pos)) {
+			continue
+		}
+		write := isValueWrittenBy(r, v)
+		if !analyzer.locks.isLocked(r, key, write) {
+			uses = append(uses, paramUse{param: p, instr: r, write: write})
+		}
+	}
+	return
+}
+
+// paramCallUses v 继续传给本包的函数时，检查被调函数中的使用；被调函数不在本包时，返回 false ，视为一次使用
+func (analyzer *BaseAnalyzer) paramCallUses(site ssa.CallInstruction, v ssa.Value, key lockKey, seen map[*ssa.Parameter]bool) (uses []paramUse, ok bool) {
+	callees := analyzer.locks.callees(site)
+	if len(callees) == 0 {
+		return nil, false
+	}
+	for _, callee := range callees {
+		if !analyzer.inPkgs(callee) {
+			return nil, false
+		}
+	}
+	if analyzer.locks.siteLocked(site, key, false) {
+		return nil, true
+	}
+	for _, callee := range callees {
+		calleeKey, ok := keyIntoCallee(key, site, callee)
+		if !ok {
+			calleeKey = lockKey{m: key.m}
+		}
+		for i, p := range callee.Params {
+			if callArg(site.Common(), i) == v {
+				uses = append(uses, analyzer.paramUses(p, p, calleeKey, seen)...)
+			}
+		}
+	}
+	return uses, true
+}
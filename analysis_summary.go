@@ -0,0 +1,189 @@
+package main
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// funcSummary 函数对 mutex 的影响（站在调用者的角度）。
+// 结构体字段 mutex 以被调函数的参数为实例，调用处通过 keyFromCallee 转换
+type funcSummary struct {
+	acquires lockSet          // 返回时仍然持有的 mutex （函数内加锁）
+	releases map[lockKey]bool // 函数内解锁了调用前已持有的 mutex
+	requires map[lockKey]bool // 调用前必须持有的 mutex
+}
+
+// summary 获取函数的加锁摘要。摘要依赖被调函数的摘要，因此沿调用图自底向上递归计算；
+// 递归调用中尚未算完的函数，视为空摘要
+func (la *lockAnalysis) summary(fn *ssa.Function) *funcSummary {
+	if sum, ok := la.summaries[fn]; ok {
+		return sum
+	}
+	if la.computing[fn] {
+		return &funcSummary{}
+	}
+	la.computing[fn] = true
+	st := la.state(fn)
+	sum := la.summarize(fn, st)
+	delete(la.computing, fn)
+	la.summaries[fn] = sum
+	return sum
+}
+
+func (la *lockAnalysis) summarize(fn *ssa.Function, st *funcLockState) *funcSummary {
+	sum := &funcSummary{
+		releases: map[lockKey]bool{},
+		requires: map[lockKey]bool{},
+	}
+	var exit lockSet
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			before, ok := st.before[instr]
+			if !ok {
+				continue
+			}
+			switch instr := instr.(type) {
+			case *ssa.Return:
+				if block == fn.Recover {
+					// panic 恢复后的返回，不计入
+					continue
+				}
+				if exit == nil {
+					exit = before
+				} else {
+					exit = exit.meet(before)
+				}
+			case *ssa.Call:
+				for _, k := range la.releasedBy(instr) {
+					if before.kindOf(k) == lockNone {
+						sum.releases[k] = true
+					}
+				}
+			case *ssa.Defer:
+				// defer 解锁在函数返回时执行
+				for _, k := range la.releasedBy(instr) {
+					if before.kindOf(k) == lockNone {
+						sum.releases[k] = true
+					}
+				}
+			}
+		}
+	}
+	if exit != nil {
+		for k := range la.deferredUnlocks(fn) {
+			exit = exit.without(k)
+		}
+		// 约定中调用前持有的：返回时仍持有的不算加锁，不再持有的算解锁
+		for k := range la.entryState(fn) {
+			if exit.kindOf(k) != lockNone {
+				exit = exit.without(k)
+			} else {
+				sum.releases[k] = true
+			}
+		}
+		if len(exit) > 0 {
+			sum.acquires = exit
+		}
+	}
+	if c := contractOf(fn); c != nil {
+		for _, l := range c.acquires {
+			if sum.acquires == nil {
+				sum.acquires = lockSet{}
+			}
+			sum.acquires[l.key] = l.kind
+		}
+		for _, l := range c.releases {
+			sum.releases[l.key] = true
+		}
+		for _, l := range c.requires {
+			sum.requires[l.key] = true
+		}
+	}
+	for k := range sum.releases {
+		sum.requires[k] = true
+	}
+	return sum
+}
+
+// deferredUnlocks 函数内 defer 解锁的 mutex
+func (la *lockAnalysis) deferredUnlocks(fn *ssa.Function) map[lockKey]bool {
+	ks := map[lockKey]bool{}
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			if d, ok := instr.(*ssa.Defer); ok {
+				for _, k := range la.releasedBy(d) {
+					ks[k] = true
+				}
+			}
+		}
+	}
+	return ks
+}
+
+// releasedBy 调用指令会解锁的 mutex ：直接调用 Unlock ，或者调用的函数摘要中有解锁
+func (la *lockAnalysis) releasedBy(instr ssa.CallInstruction) (ks []lockKey) {
+	if k, op := lockOpOf(instr.Common()); k.m != nil {
+		if op == opUnlock || op == opRUnlock {
+			ks = append(ks, k)
+		}
+		return
+	}
+	for _, callee := range la.callees(instr) {
+		for k := range la.summary(callee).releases {
+			ks = append(ks, keyFromCallee(k, instr, callee))
+		}
+	}
+	return
+}
+
+// callees 获取调用指令可能调用的函数
+func (la *lockAnalysis) callees(instr ssa.CallInstruction) (fns []*ssa.Function) {
+	if la.cg != nil {
+		if node := la.cg.Nodes[instr.Parent()]; node != nil {
+			for _, edge := range node.Out {
+				if edge.Site == instr && edge.Callee.Func != nil {
+					fns = append(fns, edge.Callee.Func)
+				}
+			}
+			if len(fns) > 0 {
+				return
+			}
+		}
+	}
+	if fn := instr.Common().StaticCallee(); fn != nil {
+		fns = append(fns, fn)
+	}
+	return
+}
+
+// applySummary 根据被调函数的摘要，计算调用之后的加锁状态；
+// 多个可能的被调函数时，都加锁才算加锁，任一解锁就算解锁
+func (la *lockAnalysis) applySummary(instr ssa.CallInstruction, s lockSet) lockSet {
+	var acquires lockSet
+	var releases []lockKey
+	for i, callee := range la.callees(instr) {
+		sum := la.summary(callee)
+		callerAcquires := lockSet{}
+		for k, kind := range sum.acquires {
+			callerAcquires[keyFromCallee(k, instr, callee)] = kind
+		}
+		if i == 0 {
+			acquires = callerAcquires
+		} else {
+			acquires = acquires.meet(callerAcquires)
+		}
+		for k := range sum.releases {
+			releases = append(releases, keyFromCallee(k, instr, callee))
+		}
+	}
+	if len(acquires) == 0 && len(releases) == 0 {
+		return s
+	}
+	for _, k := range releases {
+		s = s.without(k)
+	}
+	s = s.clone()
+	for k, kind := range acquires {
+		s[k] = kind
+	}
+	return s
+}
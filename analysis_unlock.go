@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// lastOp 路径上最近一次对 mutex 的操作，多条路径汇合时按位或
+type lastOp int
+
+const (
+	lastEntry  lastOp = 1 << iota // 本函数内还没有加锁、解锁
+	lastLock                      // Lock
+	lastRLock                     // RLock
+	lastUnlock                    // Unlock 或 RUnlock
+)
+
+// opSet 各 mutex 最近一次的操作，没有记录的 mutex 为 lastEntry
+type opSet map[lockKey]lastOp
+
+func (s opSet) clone() opSet {
+	n := make(opSet, len(s))
+	for k, v := range s {
+		n[k] = v
+	}
+	return n
+}
+
+func (s opSet) equal(o opSet) bool {
+	if len(s) != len(o) {
+		return false
+	}
+	for k, v := range s {
+		if ov, ok := o[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// lastOps 指令执行前各 mutex 最近一次的操作。ops 合并所有路径；
+// deferred 只合并注册了 defer 解锁该 mutex 的路径，返回时用来检查 defer 是否会重复解锁
+type lastOps struct {
+	ops      opSet
+	deferred opSet
+}
+
+func (s lastOps) clone() lastOps {
+	return lastOps{ops: s.ops.clone(), deferred: s.deferred.clone()}
+}
+
+func (s lastOps) equal(o lastOps) bool {
+	return s.ops.equal(o.ops) && s.deferred.equal(o.deferred)
+}
+
+// merge ops 中一边没有记录的 mutex ，该路径上为 lastEntry ；deferred 中一边没有记录的，该路径没有注册 defer ，不参与合并
+func (s lastOps) merge(o lastOps) lastOps {
+	n := lastOps{ops: opSet{}, deferred: s.deferred.clone()}
+	for k, v := range s.ops {
+		if _, ok := o.ops[k]; !ok {
+			v |= lastEntry
+		}
+		n.ops[k] = v
+	}
+	for k, v := range o.ops {
+		if _, ok := s.ops[k]; !ok {
+			v |= lastEntry
+		}
+		n.ops[k] |= v
+	}
+	for k, v := range o.deferred {
+		n.deferred[k] |= v
+	}
+	return n
+}
+
+// set 记录 k 最近一次的操作；已注册 defer 解锁 k 的路径同样记录
+func (s lastOps) set(k lockKey, op lastOp) lastOps {
+	_, deferred := s.deferred[k]
+	if s.ops[k] == op && (!deferred || s.deferred[k] == op) {
+		return s
+	}
+	n := s.clone()
+	n.ops[k] = op
+	if deferred {
+		n.deferred[k] = op
+	}
+	return n
+}
+
+// lastOps 计算每条指令执行前，各 mutex 在各路径上最近一次的操作
+func (la *lockAnalysis) lastOps(fn *ssa.Function) map[ssa.Instruction]lastOps {
+	st := la.state(fn)
+	if st.last == nil {
+		empty := lastOps{ops: opSet{}, deferred: opSet{}}
+		st.last = solve(fn, empty, empty, lastOps.merge, la.transferLastOps)
+	}
+	return st.last
+}
+
+func (la *lockAnalysis) transferLastOps(instr ssa.Instruction, s lastOps) lastOps {
+	if d, ok := instr.(*ssa.Defer); ok {
+		// 注册 defer 解锁：之后这条路径上对该 mutex 的操作，记录到 deferred
+		for _, k := range la.releasedBy(d) {
+			if _, ok := s.deferred[k]; !ok {
+				n := s.clone()
+				n.deferred[k] = lastOpOf(s.ops, k)
+				s = n
+			}
+		}
+		return s
+	}
+	c, ok := instr.(*ssa.Call)
+	if !ok {
+		return s
+	}
+	k, op := lockOpOf(&c.Call)
+	if k.m == nil {
+		if op != opNone {
+			return s
+		}
+		// 普通函数调用，使用被调函数的摘要
+		for _, callee := range la.callees(c) {
+			sum := la.summary(callee)
+			for k := range sum.releases {
+				s = s.set(keyFromCallee(k, c, callee), lastUnlock)
+			}
+			for k, kind := range sum.acquires {
+				if kind == lockRead {
+					s = s.set(keyFromCallee(k, c, callee), lastRLock)
+				} else {
+					s = s.set(keyFromCallee(k, c, callee), lastLock)
+				}
+			}
+		}
+		return s
+	}
+	switch op {
+	case opLock:
+		return s.set(k, lastLock)
+	case opRLock:
+		return s.set(k, lastRLock)
+	}
+	return s.set(k, lastUnlock)
+}
+
+// acquiresIn 函数内是否对 k 加锁：直接调用 Lock RLock ，或者调用的函数返回时仍持有
+func (la *lockAnalysis) acquiresIn(fn *ssa.Function, k lockKey) bool {
+	if len(la.lockSitesOf(fn, k)) > 0 {
+		return true
+	}
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			c, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			for _, callee := range la.callees(c) {
+				for h := range la.summary(callee).acquires {
+					if matchKey(keyFromCallee(h, c, callee), k) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// lastOpOf k 在各路径上最近一次的操作；无法确定实例时，合并同一字段所有实例的操作
+func lastOpOf(ops opSet, k lockKey) lastOp {
+	if k.knownInstance() {
+		if last, ok := ops[k]; ok {
+			return last
+		}
+		return lastEntry
+	}
+	var last lastOp
+	for h, v := range ops {
+		if h.m == k.m {
+			last |= v
+		}
+	}
+	if last == 0 {
+		last = lastEntry
+	}
+	return last
+}
+
+// checkUnlock 检查解锁操作：没有加锁就解锁、重复解锁、RLock/Unlock Lock/RUnlock 不配对
+func (analyzer *BaseAnalyzer) checkUnlock() {
+	for _, node := range analyzer.cg.Nodes {
+		if !analyzer.inPkgs(node.Func) {
+			continue
+		}
+		fn := node.Func
+		last := analyzer.locks.lastOps(fn)
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				s, ok := last[instr]
+				if !ok {
+					continue
+				}
+				switch instr := instr.(type) {
+				case *ssa.Call:
+					analyzer.checkUnlockOp(instr, &instr.Call, s.ops)
+				case *ssa.Defer:
+					analyzer.checkUnlockOp(instr, &instr.Call, s.ops)
+				case *ssa.Return:
+					// defer 解锁在返回时执行，此时不能已经解锁；只看到达该 return 的路径上注册的 defer
+					for k, op := range s.deferred {
+						if !analyzer.mutexes[k.m] {
+							continue
+						}
+						if op&lastUnlock != 0 {
+							analyzer.printUnlock(instr, fmt.Sprintf("%v 已经解锁，defer 中会重复解锁", k.m.Name()))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (analyzer *BaseAnalyzer) checkUnlockOp(instr ssa.Instruction, c *ssa.CallCommon, ops opSet) {
+	k, op := lockOpOf(c)
+	if k.m == nil && op == opNone {
+		// 调用的函数中解锁（如 unlockIt() ），按被调函数的摘要检查
+		if call, ok := instr.(*ssa.Call); ok {
+			for _, k := range analyzer.locks.releasedBy(call) {
+				analyzer.checkRelease(instr, k, op, ops)
+			}
+		}
+		return
+	}
+	if k.m == nil || (op != opUnlock && op != opRUnlock) {
+		return
+	}
+	analyzer.checkRelease(instr, k, op, ops)
+}
+
+// checkRelease 检查解锁 k ： op 为 opNone 时是调用的函数中解锁，不检查 RLock/Unlock 配对
+func (analyzer *BaseAnalyzer) checkRelease(instr ssa.Instruction, k lockKey, op lockOp, ops opSet) {
+	if !analyzer.mutexes[k.m] {
+		return
+	}
+	m := k.m
+	last := lastOpOf(ops, k)
+	if _, isDefer := instr.(*ssa.Defer); isDefer {
+		// defer 解锁：只检查配对
+		last &^= lastUnlock | lastEntry
+	}
+	if last&lastUnlock != 0 {
+		analyzer.printUnlock(instr, fmt.Sprintf("%v 可能重复解锁", m.Name()))
+	}
+	// 本函数内有加锁，但有路径没有加锁就解锁；本函数内没有加锁的，视为解锁调用者持有的锁
+	if last&lastEntry != 0 && analyzer.locks.acquiresIn(instr.Parent(), k) {
+		analyzer.printUnlock(instr, fmt.Sprintf("%v 可能没有加锁就解锁", m.Name()))
+	}
+	if op == opUnlock && last&lastRLock != 0 {
+		analyzer.printUnlock(instr, fmt.Sprintf("%v RLock 后调用了 Unlock ，请使用 RUnlock", m.Name()))
+	}
+	if op == opRUnlock && last&lastLock != 0 {
+		analyzer.printUnlock(instr, fmt.Sprintf("%v Lock 后调用了 RUnlock ，请使用 Unlock", m.Name()))
+	}
+}
+
+func (analyzer *BaseAnalyzer) printUnlock(instr ssa.Instruction, msg string) {
+	pos := analyzer.prog.Fset.Position(instr.Pos())
+	if !pos.IsValid() && instr.Parent().Syntax() != nil {
+		pos = analyzer.prog.Fset.Position(instr.Parent().Syntax().End())
+	}
+	if nolint(getComment(pos)) {
+		return
+	}
+	analyzer.PrintsLock = append(analyzer.PrintsLock, fmt.Sprintf("[mutex check] %v:%v %v 。", pos.Filename, pos.Line, msg))
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"go/ast"
 	"go/token"
 	"go/types"
 	"sort"
@@ -15,22 +14,30 @@ import (
 
 type IAnalysis interface {
 	FindVar(pass *analysis.Pass)
-	FindCaller(*callgraph.Edge, map[*callgraph.Node]bool) error
-	CheckVarLock(prog *ssa.Program, caller *callgraph.Node, mymutex, myvar *types.Var) []token.Position
+	FindCaller(*callgraph.Node, map[*callgraph.Node]bool) error
+	CheckVarLock(prog *ssa.Program, caller *callgraph.Node, mymutex, myvar *types.Var) []varAccess
 	HaveVar(prog *ssa.Program, caller *callgraph.Node, m *types.Var) bool
-	CheckCallLock(prog *ssa.Program, caller *callgraph.Node, mymutex *types.Var, callee *callgraph.Node) bool
+	CheckCallLock(prog *ssa.Program, caller *callgraph.Node, key lockKey, callee *callgraph.Node, write bool) bool
 	CheckVarReturn(prog *ssa.Program, caller *callgraph.Node, myvar *types.Var) []token.Position
 }
 
 func (analyzer *BaseAnalyzer) runOne(prog *ssa.Program, pass *analysis.Pass) (interface{}, error) {
+	analyzer.pkgs[pass.Pkg.Path()] = true
 	analyzer.Derive.FindVar(pass)
 	return nil, nil
 }
 
 func (analyzer *BaseAnalyzer) step2FindCaller() {
+	// 遍历所有节点，没有调用其他函数的叶子函数也要检查
 	seen := make(map[*callgraph.Node]bool)
-	if err := callgraph.GraphVisitEdges(analyzer.cg, func(edge *callgraph.Edge) error { return analyzer.Derive.FindCaller(edge, seen) }); err != nil {
-		panic(err)
+	for _, node := range analyzer.cg.Nodes {
+		// 只检查本次分析的包中的访问（要锁的变量可以在 import 的包中）
+		if ignoredFunc(node.Func) || !analyzer.inPkgs(node.Func) {
+			continue
+		}
+		if err := analyzer.Derive.FindCaller(node, seen); err != nil {
+			panic(err)
+		}
 	}
 }
 
@@ -42,7 +49,7 @@ func (analyzer *BaseAnalyzer) step3CutCaller() {
 			poss := analyzer.Derive.CheckVarLock(analyzer.prog, caller, m, v)
 			if len(poss) != 0 {
 				if _, ok := analyzer.callers2[v]; !ok {
-					analyzer.callers2[v] = make(map[*callgraph.Node][]token.Position)
+					analyzer.callers2[v] = make(map[*callgraph.Node][]varAccess)
 				}
 				analyzer.callers2[v][caller] = poss
 			}
@@ -65,7 +72,8 @@ func (analyzer *BaseAnalyzer) step3CutCaller() {
 	}
 }
 
-func (analyzer *BaseAnalyzer) step4CheckPath(myvar *types.Var, target *callgraph.Node, paths []*callgraph.Node, seen map[*callgraph.Node]bool, checkFail *string) {
+// key 是 paths[0] （ paths 为空时是 target ）中要持有的 mutex
+func (analyzer *BaseAnalyzer) step4CheckPath(myvar *types.Var, key lockKey, write bool, target *callgraph.Node, paths []*callgraph.Node, seen map[*callgraph.Node]bool, checkFail *string, rlocked, otherInstance *bool) {
 	if seen[target] {
 		return
 	}
@@ -84,11 +92,23 @@ func (analyzer *BaseAnalyzer) step4CheckPath(myvar *types.Var, target *callgraph
 		}
 	}
 
-	// 检查是否有 mutex (上层函数)
-	mymutex := analyzer.vars[myvar]
-	if len(newPaths) > 1 && analyzer.Derive.HaveVar(analyzer.prog, target, mymutex) {
+	// 检查是否有 mutex (上层函数，包括通过调用其他函数加锁)
+	mymutex := key.m
+	if len(newPaths) > 1 && (analyzer.Derive.HaveVar(analyzer.prog, target, mymutex) || analyzer.locks.mayHold(target.Func, mymutex)) {
 		// 检查调用在 mutex lock 中
-		if analyzer.Derive.CheckCallLock(analyzer.prog, target, mymutex, newPaths[1]) {
+		if analyzer.Derive.CheckCallLock(analyzer.prog, target, key, newPaths[1], write) {
+			return
+		}
+		// 写操作，但上层函数只持有 RLock
+		if write && analyzer.Derive.CheckCallLock(analyzer.prog, target, key, newPaths[1], false) {
+			*checkFail = printPaht(newPaths, looped)
+			*rlocked = true
+			return
+		}
+		// 上层函数加锁的是其他实例
+		if key.root != nil && analyzer.Derive.CheckCallLock(analyzer.prog, target, lockKey{m: key.m}, newPaths[1], write) {
+			*checkFail = printPaht(newPaths, looped)
+			*otherInstance = true
 			return
 		}
 	}
@@ -109,24 +129,53 @@ func (analyzer *BaseAnalyzer) step4CheckPath(myvar *types.Var, target *callgraph
 		*checkFail = printPaht(newPaths, looped)
 		return
 	} else {
+		// key 转换为 target 中的实例
+		if len(paths) > 0 {
+			key = keyInCaller(key, target, paths[0])
+		}
 		for _, in := range target.In {
-			analyzer.step4CheckPath(myvar, in.Caller, newPaths, seen, checkFail)
+			// go 启动的协程，不会继承上层调用持有的锁
+			if _, ok := in.Site.(*ssa.Go); ok {
+				*checkFail = printPaht(append([]*callgraph.Node{in.Caller}, newPaths...), looped)
+				return
+			}
+			analyzer.step4CheckPath(myvar, key, write, in.Caller, newPaths, seen, checkFail, rlocked, otherInstance)
 		}
 	}
 }
 
+// callLocked 检查 caller 中对 callee 的调用，是否都在 mutex 加锁范围内。
+// key 是 callee 中要持有的 mutex ，经调用参数转换为调用处的实例；无法确定实例时，任一实例都算
+func (analyzer *BaseAnalyzer) callLocked(caller *callgraph.Node, key lockKey, callee *callgraph.Node, write bool) bool {
+	for _, site := range getCalleeSites(caller, callee) {
+		comment := getComment(analyzer.prog.Fset.Position(site.Pos()))
+		if nolint(comment) {
+			continue
+		}
+		if !analyzer.locks.siteLocked(site, keyFromCallee(key, site, callee.Func), write) {
+			return false
+		}
+	}
+	return true
+}
+
 type BaseAnalyzer struct {
 	*analysis.Analyzer
 	path         string
 	cg           *callgraph.Graph
 	prog         *ssa.Program
-	vars         map[*types.Var]*types.Var // key : 变量； value mutex
+	vars         map[*types.Var]*types.Var  // key : 变量； value mutex
+	mutexes      map[*types.Var]bool        // 有注释的 mutex
+	paths        map[*types.Var][]guardPath // 嵌套路径（如 stats.hits items[*].state ）上要锁的字段
+	pkgs         map[string]bool            // 要检查的包
 	callers      map[*types.Var]map[*callgraph.Node][]token.Position
-	callers2     map[*types.Var]map[*callgraph.Node][]token.Position // 没直接加锁的 call
+	callers2     map[*types.Var]map[*callgraph.Node][]varAccess      // 没直接加锁的 call
 	callers3     map[*types.Var]map[*callgraph.Node][]token.Position // 含 return 的 call
 	PrintsCall   sort.StringSlice
 	PrintsReturn sort.StringSlice
+	PrintsLock   sort.StringSlice
 	Derive       IAnalysis
+	locks        *lockAnalysis
 }
 
 func NewBaseAnalyzer(path string, cg *callgraph.Graph, prog *ssa.Program) *BaseAnalyzer {
@@ -135,9 +184,13 @@ func NewBaseAnalyzer(path string, cg *callgraph.Graph, prog *ssa.Program) *BaseA
 		cg:       cg,
 		prog:     prog,
 		vars:     map[*types.Var]*types.Var{},
+		mutexes:  map[*types.Var]bool{},
+		paths:    map[*types.Var][]guardPath{},
+		pkgs:     map[string]bool{},
 		callers:  map[*types.Var]map[*callgraph.Node][]token.Position{},
-		callers2: map[*types.Var]map[*callgraph.Node][]token.Position{},
+		callers2: map[*types.Var]map[*callgraph.Node][]varAccess{},
 		callers3: map[*types.Var]map[*callgraph.Node][]token.Position{},
+		locks:    newLockAnalysis(prog, cg),
 	}
 	analyzer.Analyzer = &analysis.Analyzer{
 		Name: "mutex_check",
@@ -157,56 +210,69 @@ func (analyzer *BaseAnalyzer) Analysis() {
 	// 3. 剔除 B 中有加锁的函数，得 C
 	analyzer.step3CutCaller()
 	// 4. 查看调用关系，逆向检查上级调用是否加锁
-	seen := make(map[string]bool)
-	var keys sort.StringSlice
-	m := map[string]*types.Var{}
+	seen := make(map[reportKey]bool)
+	var vars []*types.Var
 	for v := range analyzer.callers2 {
-		n := fmt.Sprintf("%v_%v", v.Pkg().Path(), v.Name())
-		if _, ok := m[n]; ok {
-			continue
-		}
-		keys = append(keys, n)
-		m[n] = v
+		vars = append(vars, v)
 	}
-	sort.Sort(keys)
-	for _, key := range keys {
-		v := m[key]
+	analyzer.sortVars(vars)
+	for _, v := range vars {
 		nodes := analyzer.callers2[v]
-		for node, varCallPos := range nodes {
-			var checkFail string
-			analyzer.step4CheckPath(v, node, []*callgraph.Node{}, map[*callgraph.Node]bool{}, &checkFail)
-			if checkFail != "" {
-				if _, ok := seen[checkFail]; !ok {
-					for _, pos := range varCallPos {
-						var s string
-						if pos.Filename != "" && pos.Line != 0 {
-							s = fmt.Sprintf("[mutex check] %v:%v 没有调用 mutex lock/unlock 。", pos.Filename, pos.Line)
-						}
-						if s != "" {
-							analyzer.PrintsCall = append(analyzer.PrintsCall, s)
+		for node, accesses := range nodes {
+			var groups [][]varAccess // 按读写、要持有的 mutex 实例分组，读在前
+			for _, access := range accesses {
+				if access.rlocked {
+					// 本函数内只持有 RLock ，直接报错
+					analyzer.PrintsCall = append(analyzer.PrintsCall, fmt.Sprintf("[mutex check] %v:%v 持有 RLock 时写入，请使用 Lock 。", access.Filename, access.Line))
+				} else if access.otherInstance {
+					// 本函数内加锁的是其他实例，直接报错
+					analyzer.PrintsCall = append(analyzer.PrintsCall, fmt.Sprintf("[mutex check] %v:%v 加锁的是其他实例，没有锁住该变量 。", access.Filename, access.Line))
+				} else {
+					groups = addAccess(groups, access)
+				}
+			}
+			sort.SliceStable(groups, func(i, j int) bool { return !groups[i][0].write && groups[j][0].write })
+			for _, varCallPos := range groups {
+				write := varCallPos[0].write
+				var checkFail string
+				var rlocked, otherInstance bool
+				analyzer.step4CheckPath(v, varCallPos[0].key, write, node, []*callgraph.Node{}, map[*callgraph.Node]bool{}, &checkFail, &rlocked, &otherInstance)
+				if checkFail != "" {
+					key := reportKey{checkFail, write, varCallPos[0].key}
+					if _, ok := seen[key]; !ok {
+						for _, pos := range varCallPos {
+							var s string
+							if pos.Filename != "" && pos.Line != 0 {
+								if rlocked {
+									s = fmt.Sprintf("[mutex check] %v:%v 上层调用只持有 RLock 时写入，请使用 Lock 。", pos.Filename, pos.Line)
+								} else if otherInstance {
+									s = fmt.Sprintf("[mutex check] %v:%v 上层调用加锁的是其他实例，没有锁住该变量 。", pos.Filename, pos.Line)
+								} else if pos.iter {
+									s = fmt.Sprintf("[mutex check] %v:%v range 遍历 %v 时没有持有 %v 。", pos.Filename, pos.Line, pos.name(v), pos.key.m.Name())
+								} else if pos.desc != "" {
+									s = fmt.Sprintf("[mutex check] %v:%v 访问 %v 没有调用 mutex lock/unlock 。", pos.Filename, pos.Line, pos.desc)
+								} else {
+									s = fmt.Sprintf("[mutex check] %v:%v 没有调用 mutex lock/unlock 。", pos.Filename, pos.Line)
+								}
+							}
+							if s != "" {
+								analyzer.PrintsCall = append(analyzer.PrintsCall, s)
+							}
 						}
 					}
+					seen[key] = true
 				}
-				seen[checkFail] = true
 			}
-			checkFail = ""
 		}
 	}
 
 	//
-	keys = sort.StringSlice{}
-	m = map[string]*types.Var{}
+	vars = nil
 	for v := range analyzer.callers3 {
-		n := fmt.Sprintf("%v_%v", v.Pkg().Path(), v.Name())
-		if _, ok := m[n]; ok {
-			continue
-		}
-		keys = append(keys, n)
-		m[n] = v
+		vars = append(vars, v)
 	}
-	sort.Sort(keys)
-	for _, key := range keys {
-		v := m[key]
+	analyzer.sortVars(vars)
+	for _, v := range vars {
 		nodes := analyzer.callers3[v]
 		for _, varCallPos := range nodes {
 			for _, pos := range varCallPos {
@@ -221,129 +287,43 @@ func (analyzer *BaseAnalyzer) Analysis() {
 		}
 	}
 
+	// 5. 检查持有 mutex 时重复加锁
+	analyzer.checkSelfDeadlock()
+	// 6. 检查加锁后没有解锁就返回
+	analyzer.checkLockLeak()
+	// 7. 检查解锁与加锁是否配对
+	analyzer.checkUnlock()
+	// 8. 检查解锁后传给其他函数的要锁变量
+	analyzer.checkParams()
+	// 9. 检查解锁后通过局部变量等使用的要锁变量
+	analyzer.checkAliases()
+	// 10. 检查调用约定了 requires releases 的函数时是否持有锁
+	analyzer.checkContracts()
 }
 
-func isSyncMutexType(expr ast.Expr) bool {
-	ident, ok := expr.(*ast.SelectorExpr)
-	if !ok || ident.X == nil || ident.Sel == nil {
+// isMutexType 是否是 mutex 类型：锁类型（ sync.Mutex sync.RWMutex sync.Locker 及 --locktypes 配置的，包括指针、类型别名），
+// 以及只包装了 mutex 的类型（如 type Mu struct{ sync.Mutex }）
+func isMutexType(t types.Type) bool {
+	if t == nil {
 		return false
 	}
-	x, ok := ident.X.(*ast.Ident)
-	sel := ident.Sel
-	if !ok {
-		return false
+	if lockTypeOf(t) != nil {
+		return true
 	}
-	return sel.Name == "Mutex" && x.Name == "sync"
-}
-
-func isSyncRWMutexType(expr ast.Expr) bool {
-	ident, ok := expr.(*ast.SelectorExpr)
-	if !ok || ident.X == nil || ident.Sel == nil {
-		return false
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		t = p.Elem()
 	}
-	x, ok := ident.X.(*ast.Ident)
-	sel := ident.Sel
-	if !ok {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || st.NumFields() == 0 {
 		return false
 	}
-	return sel.Name == "RWMutex" && x.Name == "sync"
-}
-
-func isMutexType(expr ast.Expr) bool {
-	switch expr2 := expr.(type) {
-	case *ast.StarExpr:
-		return isMutexType(expr2.X)
-	}
-	return isSyncMutexType(expr) || isSyncRWMutexType(expr)
-}
-
-func isMutexType2(v *ast.ValueSpec) bool {
-	var expr ast.Expr
-	if v.Type != nil {
-		expr = v.Type
-	} else {
-		expr = v.Values[0]
-		switch expr2 := expr.(type) {
-		case *ast.UnaryExpr:
-			expr = expr2.X.(*ast.CompositeLit).Type
-		case *ast.CompositeLit:
-			expr = expr2.Type
-		}
-	}
-	return isSyncMutexType(expr) || isSyncRWMutexType(expr)
-}
-
-func checkMutexLock(prog *ssa.Program, mInstrs []ssa.Instruction, vPos token.Position) bool {
-	if mInstrs == nil {
-		return false
-	}
-	if mInstrs != nil {
-		// 如果有 defer unlock ，只要看有 Lock 在前面
-		var hasDefer bool
-		for _, instr := range mInstrs {
-			if d, ok := instr.(*ssa.Defer); ok && (d.Call.Value.Name() == "Unlock" || d.Call.Value.Name() == "RUnlock") {
-				hasDefer = true
-				break
-			}
-		}
-		if hasDefer {
-			for i := 0; i < len(mInstrs); i++ {
-				switch c := mInstrs[i].(type) {
-				case *ssa.Defer:
-					continue
-				case *ssa.Call:
-					n := c.Call.Value.Name()
-					if n == "Unlock" || n == "RUnlock" {
-						continue
-					}
-					mPos1 := prog.Fset.Position(mInstrs[i].Pos())
-					if vPos.Line > mPos1.Line {
-						return true
-					}
-				}
-			}
-		} else {
-			// 否则，查看是否变量/调用在  lock unlock 中间
-			m := make(map[token.Position]token.Position)
-			for i := 0; i < len(mInstrs); i++ {
-				if c, ok := mInstrs[i].(*ssa.Call); ok {
-					n := c.Call.Value.Name()
-					if n == "Unlock" || n == "RUnlock" {
-						continue
-					}
-					// 找 lock
-					mPos1 := prog.Fset.Position(mInstrs[i].Pos())
-					// 找 unlock
-					var mPos2 token.Position
-					for j := i + 1; j < len(mInstrs); j++ {
-						if c, ok := mInstrs[j].(*ssa.Call); ok {
-							n := c.Call.Value.Name()
-							if n == "Lock" || n == "RLock" {
-								break
-							}
-							mPos2 = prog.Fset.Position(mInstrs[j].Pos())
-						}
-					}
-					m[mPos1] = mPos2
-				}
-			}
-			for pos1, pos2 := range m {
-				if !pos2.IsValid() {
-					continue
-				}
-				if pos2.Line != 0 {
-					if vPos.Line > pos1.Line && vPos.Line < pos2.Line {
-						return true
-					}
-				} else {
-					if vPos.Line > pos1.Line {
-						return true
-					}
-				}
-			}
+	for i := 0; i < st.NumFields(); i++ {
+		if !isMutexType(st.Field(i).Type()) {
+			return false
 		}
 	}
-	return false
+	// 方法集中要有锁类型的加锁方法（嵌入 mutex）
+	return promotedLockType(t) != nil
 }
 
 func printPaht(newPath []*callgraph.Node, looped bool) string {
@@ -360,29 +340,83 @@ func printPaht(newPath []*callgraph.Node, looped bool) string {
 func isGoroutine(fn *ssa.Function) bool {
 	if fn.Referrers() != nil {
 		for _, r := range *fn.Referrers() {
-			if _, ok := r.(*ssa.Go); ok {
+			switch r := r.(type) {
+			case *ssa.Go:
 				return true
+			case *ssa.MakeClosure:
+				// 有捕获变量的匿名函数，通过 MakeClosure 启动
+				if r.Fn == fn && r.Referrers() != nil {
+					for _, rr := range *r.Referrers() {
+						if g, ok := rr.(*ssa.Go); ok && g.Call.Value == r {
+							return true
+						}
+					}
+				}
 			}
 		}
 	}
 	return false
 }
 
-func getCalleePostion(prog *ssa.Program, caller *callgraph.Node, callee *callgraph.Node) (p []token.Position) {
-	for _, block := range caller.Func.Blocks {
-		for _, instr := range block.Instrs {
-			if c, ok := instr.(*ssa.Call); ok {
-				if f, ok := c.Call.Value.(*ssa.Function); ok && f == callee.Func {
-					p = append(p, prog.Fset.Position(instr.Pos()))
-				}
-			}
+// sortVars 按声明位置排序，使输出稳定（不同结构体中可以有同名字段）
+func (analyzer *BaseAnalyzer) sortVars(vars []*types.Var) {
+	sort.Slice(vars, func(i, j int) bool {
+		a, b := analyzer.prog.Fset.Position(vars[i].Pos()), analyzer.prog.Fset.Position(vars[j].Pos())
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+}
+
+// keyInCaller 把 callee 中的 key 转换为 caller 中的实例；各调用处的实例不同时，任一实例都算
+func keyInCaller(key lockKey, caller, callee *callgraph.Node) lockKey {
+	var k lockKey
+	for i, site := range getCalleeSites(caller, callee) {
+		sk := keyFromCallee(key, site, callee.Func)
+		if i > 0 && sk != k {
+			return lockKey{m: key.m}
 		}
+		k = sk
+	}
+	if k.m == nil {
+		return lockKey{m: key.m}
+	}
+	return k
+}
+
+// reportKey 相同调用路径、读写、mutex 实例的访问只报一次
+type reportKey struct {
+	path  string
+	write bool
+	key   lockKey
+}
+
+// addAccess 把访问加入读写、mutex 实例都相同的分组
+func addAccess(groups [][]varAccess, access varAccess) [][]varAccess {
+	for i, g := range groups {
+		if g[0].write == access.write && g[0].key == access.key {
+			groups[i] = append(g, access)
+			return groups
+		}
+	}
+	return append(groups, []varAccess{access})
+}
+
+// getCalleeSites 获取 caller 中调用 callee 的指令（包括 go defer）
+func getCalleeSites(caller *callgraph.Node, callee *callgraph.Node) (sites []ssa.CallInstruction) {
+	for _, edge := range caller.Out {
+		if edge.Callee != callee || edge.Site == nil {
+			continue
+		}
+		sites = append(sites, edge.Site)
 	}
 	return
 }
 
 func nolint(comment string) bool {
-	return strings.Contains(comment, "nolint") && strings.Contains(comment, "mutex_check")
+	// 兼容 gVisor checklocks 的 +checklocksignore
+	return strings.Contains(comment, "nolint") && strings.Contains(comment, "mutex_check") || strings.Contains(comment, "+checklocksignore")
 }
 
 func hasVar(v ssa.Value, myvar *types.Var) bool {
@@ -400,6 +434,25 @@ func hasVar(v ssa.Value, myvar *types.Var) bool {
 	}
 	switch v := v.(type) {
 	case *ssa.UnOp:
+		switch x := v.X.(type) {
+		case *ssa.IndexAddr:
+			if !isReferenceType(v.Type()) {
+				// 取出的元素是值拷贝
+				return false
+			}
+		case *ssa.Alloc:
+			// 有 defer 时，返回值经过局部变量：检查同一基本块中之前最后一次保存的值
+			var val ssa.Value
+			for _, instr := range v.Block().Instrs {
+				if instr == v {
+					break
+				}
+				if store, ok := instr.(*ssa.Store); ok && store.Addr == x {
+					val = store.Val
+				}
+			}
+			return val != nil && hasVar(val, myvar)
+		}
 		return hasVar(v.X, myvar)
 	case *ssa.FieldAddr:
 		if pointerType, ok := v.X.Type().Underlying().(*types.Pointer); ok {
@@ -419,22 +472,23 @@ func hasVar(v ssa.Value, myvar *types.Var) bool {
 	case *ssa.Alloc:
 	case *ssa.Call:
 	case *ssa.Lookup:
-		// return hasVar(v.X, myvar)
+		// map 元素是 map slice 指针时，仍引用要锁的内容
+		if isReferenceType(v.Type()) {
+			return hasVar(v.X, myvar)
+		}
 	case *ssa.Extract:
-		// switch v := v.Tuple.(type) {
-		// case *ssa.Call:
-		// case *ssa.Lookup:
-		// 	return hasVar(v.X, myvar)
-		// case *ssa.Next:
-		// 	switch v := v.Iter.(type) {
-		// 	case *ssa.Range:
-		// 		return hasVar(v.X, myvar)
-		// 	default:
-		// 		panic("发现还有其他类型需要处理下 #3")
-		// 	}
-		// default:
-		// 	panic("发现还有其他类型需要处理下 #2")
-		// }
+		if !isReferenceType(v.Type()) {
+			break
+		}
+		switch t := v.Tuple.(type) {
+		case *ssa.Lookup:
+			return hasVar(t.X, myvar)
+		case *ssa.Next:
+			// range 遍历得到的 key value
+			if r, ok := t.Iter.(*ssa.Range); ok {
+				return hasVar(r.X, myvar)
+			}
+		}
 	case *ssa.MakeMap:
 	case *ssa.MakeChan:
 	case *ssa.MakeSlice:
@@ -447,7 +501,8 @@ func hasVar(v ssa.Value, myvar *types.Var) bool {
 		}
 	case *ssa.BinOp:
 	case *ssa.IndexAddr:
-		// return hasVar(v.X, myvar)
+		// 元素的地址
+		return hasVar(v.X, myvar)
 	case *ssa.TypeAssert:
 		return hasVar(v.X, myvar)
 	case *ssa.ChangeType:
@@ -13,27 +13,39 @@ import (
 )
 
 func (analyzer *VarAnalyzer) FindVar(pass *analysis.Pass) {
+	refs := guardRefs(pass)
 	for _, file := range pass.Files {
 		for _, decl := range file.Decls {
 			genDecl, ok := decl.(*ast.GenDecl)
 			if !ok || genDecl.Tok != token.VAR {
 				continue
 			}
-			var mutexValueSpecs []*ast.ValueSpec
+			type mutexSpec struct {
+				*ast.ValueSpec
+				ident *ast.Ident
+			}
+			var mutexValueSpecs []mutexSpec
 			for _, spec := range genDecl.Specs {
 				if valueSpec, ok := spec.(*ast.ValueSpec); ok {
-					if !isMutexType2(valueSpec) {
-						continue
-					}
-					ident := valueSpec.Names[0]
-					obj := pass.TypesInfo.Defs[ident]
-					if obj == nil {
-						continue
-					}
-					v, _ := obj.(*types.Var)
-					isGlobal := !v.IsField() && !v.Embedded() && v.Parent() == pass.Pkg.Scope() // 全局变量
-					if isGlobal {
-						mutexValueSpecs = append(mutexValueSpecs, valueSpec)
+					for _, ident := range valueSpec.Names {
+						obj := pass.TypesInfo.Defs[ident]
+						if obj == nil {
+							continue
+						}
+						v, _ := obj.(*types.Var)
+						isGlobal := v != nil && !v.IsField() && !v.Embedded() && v.Parent() == pass.Pkg.Scope() // 全局变量
+						if !isGlobal {
+							continue
+						}
+						// 按类型判断，支持 import 别名、类型别名、包装类型、函数返回值初始化
+						if isMutexType(v.Type()) {
+							mutexValueSpecs = append(mutexValueSpecs, mutexSpec{valueSpec, ident})
+						} else if !ignored(valueSpec.Doc, valueSpec.Comment) {
+							// 变量注释指明锁它的 mutex ，可以是其他全局变量的字段
+							for _, n := range valueGuardNames(pass.Fset, genDecl, valueSpec) {
+								analyzer.addGuardedBy(pass, ident, n)
+							}
+						}
 					}
 				}
 			}
@@ -41,40 +53,46 @@ func (analyzer *VarAnalyzer) FindVar(pass *analysis.Pass) {
 				continue
 			}
 			for _, mutexValueSpec := range mutexValueSpecs {
-				pos := pass.Fset.Position(mutexValueSpec.Pos())
+				pos := pass.Fset.Position(mutexValueSpec.ident.Pos())
 				mutexVar := analyzer.getGlobalVarByPos(analyzer.prog, pos)
-				comment := ""
-				if mutexValueSpec.Comment != nil {
-					comment = strings.ReplaceAll(mutexValueSpec.Comment.Text(), " ", "")
-					comment = strings.ReplaceAll(comment, "\n", "")
+				doc := mutexValueSpec.Doc
+				if doc == nil && !genDecl.Lparen.IsValid() {
+					// var mu sync.Mutex 的文档注释记录在 GenDecl 上
+					doc = genDecl.Doc
 				}
-				if comment == "" {
+				annot := parseMutexAnnotation(pass.Fset, doc, mutexValueSpec.Comment)
+				if !annot.found {
+					// 被 guarded_by 引用的，可以不加注释
+					if refs[pass.TypesInfo.Defs[mutexValueSpec.ident].(*types.Var)] {
+						analyzer.mutexes[mutexVar] = true
+						continue
+					}
 					fmt.Printf("[mutex check] %v:%v mutex 变量没有注释，指明它要锁的变量\n", pos.Filename, pos.Line)
 					continue
 				}
-				if nolint(comment) {
+				if annot.nolint {
 					continue
 				}
-				varNames := strings.Split(comment, ",")
-				for _, name := range varNames {
-					valueSpec := analyzer.getGlobalVarByName(pass, file, name)
-					if valueSpec == nil {
-						pos := pass.Fset.Position(mutexValueSpec.Pos())
-						fmt.Printf("[mutex check] %v:%v mutex 变量注释中的变量 %v ，未声明\n", pos.Filename, pos.Line, name)
-						break
-					} else {
-						pos := pass.Fset.Position(valueSpec.Pos())
-						v := analyzer.getGlobalVarByPos(analyzer.prog, pos)
-						analyzer.vars[v] = mutexVar
+				analyzer.mutexes[mutexVar] = true
+				for _, n := range annot.names {
+					v := analyzer.lookupGlobalVar(pass, file, n.name)
+					if v == nil && isNestedPath(pass, file, n.name) {
+						pos := pass.Fset.Position(n.pos)
+						fmt.Printf("[mutex check] %v:%v mutex 变量注释中的 %v ，嵌套路径只支持结构体字段 mutex\n", pos.Filename, pos.Line, n.name)
+						continue
 					}
+					if v == nil {
+						printUndeclared(pass.Fset, n, globalVarNames(pass))
+						continue
+					}
+					analyzer.addGuard(v, mutexVar)
 				}
 			}
 		}
 	}
 }
 
-func (analyzer *VarAnalyzer) FindCaller(edge *callgraph.Edge, seen map[*callgraph.Node]bool) error {
-	caller := edge.Caller
+func (analyzer *VarAnalyzer) FindCaller(caller *callgraph.Node, seen map[*callgraph.Node]bool) error {
 	if seen[caller] {
 		return nil
 	}
@@ -113,21 +131,20 @@ func (analyzer *VarAnalyzer) FindCaller(edge *callgraph.Edge, seen map[*callgrap
 	return nil
 }
 
-func (analyzer *VarAnalyzer) CheckVarLock(prog *ssa.Program, caller *callgraph.Node, mymutex, myvar *types.Var) (poss []token.Position) {
-	var mInstrs []ssa.Instruction
-	var vInstrs []ssa.Instruction
+func (analyzer *VarAnalyzer) CheckVarLock(prog *ssa.Program, caller *callgraph.Node, mymutex, myvar *types.Var) (poss []varAccess) {
 	for _, block := range caller.Func.Blocks {
-		mInstrs = append(mInstrs, analyzer.findInstrByGlobalVar(block, mymutex)...)
-		vInstrs = append(vInstrs, analyzer.findInstrByGlobalVar(block, myvar)...)
-	}
-	for _, vInstr := range vInstrs {
-		vPos := prog.Fset.Position(vInstr.Pos())
-		comment := getComment(vPos)
-		if nolint(comment) {
-			continue
-		}
-		if !checkMutexLock(prog, mInstrs, vPos) {
-			poss = append(poss, caller.Func.Prog.Fset.Position(vInstr.Pos()))
+		for _, vInstr := range analyzer.findInstrByGlobalVar(block, myvar) {
+			vPos := prog.Fset.Position(vInstr.Pos())
+			comment := getComment(vPos)
+			if nolint(comment) {
+				continue
+			}
+			write := isWriteAccess(vInstr, globalOf(prog, myvar))
+			key := lockKey{m: mymutex}
+			if !analyzer.locks.isLocked(vInstr, key, write) {
+				rlocked := write && analyzer.locks.isLocked(vInstr, key, false)
+				poss = append(poss, varAccess{Position: vPos, key: key, write: write, rlocked: rlocked, iter: isRangeAccess(vInstr)})
+			}
 		}
 	}
 	return
@@ -145,21 +162,8 @@ func (analyzer *VarAnalyzer) HaveVar(prog *ssa.Program, caller *callgraph.Node,
 	return find
 }
 
-func (analyzer *VarAnalyzer) CheckCallLock(prog *ssa.Program, caller *callgraph.Node, mymutex *types.Var, callee *callgraph.Node) bool {
-	var mInstrs []ssa.Instruction
-	for _, block := range caller.Func.Blocks {
-		mInstrs = append(mInstrs, analyzer.findInstrByGlobalVar(block, mymutex)...)
-	}
-	for _, vPos := range getCalleePostion(prog, caller, callee) {
-		comment := getComment(vPos)
-		if nolint(comment) {
-			continue
-		}
-		if !checkMutexLock(prog, mInstrs, vPos) {
-			return false
-		}
-	}
-	return true
+func (analyzer *VarAnalyzer) CheckCallLock(prog *ssa.Program, caller *callgraph.Node, key lockKey, callee *callgraph.Node, write bool) bool {
+	return analyzer.callLocked(caller, key, callee, write)
 }
 
 func (analyzer *VarAnalyzer) CheckVarReturn(prog *ssa.Program, caller *callgraph.Node, myvar *types.Var) (poss []token.Position) {
@@ -182,6 +186,30 @@ func (analyzer *VarAnalyzer) CheckVarReturn(prog *ssa.Program, caller *callgraph
 	return
 }
 
+// globalVarNames 包内（不是 mutex 的）全局变量名，用于拼写建议
+func globalVarNames(pass *analysis.Pass) (names []string) {
+	for _, name := range pass.Pkg.Scope().Names() {
+		if v, ok := pass.Pkg.Scope().Lookup(name).(*types.Var); ok && !isMutexType(v.Type()) {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// addGuardedBy 全局变量 ident 的注释 // guarded_by: name
+func (analyzer *VarAnalyzer) addGuardedBy(pass *analysis.Pass, ident *ast.Ident, n annotName) {
+	pos := pass.Fset.Position(ident.Pos())
+	m := analyzer.resolveGuard(pass, n)
+	if m == nil {
+		npos := pass.Fset.Position(n.pos)
+		fmt.Printf("[mutex check] %v:%v guarded_by 中的 mutex %v ，未声明\n", npos.Filename, npos.Line, n.name)
+		return
+	}
+	if v := analyzer.getGlobalVarByPos(analyzer.prog, pos); v != nil {
+		analyzer.addGuard(v, m)
+	}
+}
+
 func (analyzer *VarAnalyzer) getGlobalVarByPos(prog *ssa.Program, pos token.Position) *types.Var {
 	for _, pkg := range prog.AllPackages() {
 		for _, member := range pkg.Members {
@@ -207,30 +235,50 @@ func (analyzer *VarAnalyzer) findInstrByGlobalVar(block *ssa.BasicBlock, v *type
 	return
 }
 
-func (analyzer *VarAnalyzer) getGlobalVarByName(pass *analysis.Pass, file *ast.File, name string) *ast.ValueSpec {
-	for _, decl := range file.Decls {
-		genDecl, ok := decl.(*ast.GenDecl)
-		if !ok || genDecl.Tok != token.VAR {
-			continue
-		}
-		for _, spec := range genDecl.Specs {
-			if valueSpec, ok := spec.(*ast.ValueSpec); ok {
-				ident := valueSpec.Names[0]
-				obj := pass.TypesInfo.Defs[ident]
-				if obj == nil {
-					continue
-				}
-				v, _ := obj.(*types.Var)
-				isGlobal := !v.IsField() && !v.Embedded() && v.Parent() == pass.Pkg.Scope() // 全局变量
-				if isGlobal && ident.Name == name {
-					return valueSpec
-				}
-			}
+// lookupGlobalVar 按名字查找全局变量（ prog 中的），在整个包的作用域中查找，不限于 mutex 所在的文件；
+// pkg.name 形式的，查找本包或 file 中 import 的包
+func (analyzer *VarAnalyzer) lookupGlobalVar(pass *analysis.Pass, file *ast.File, name string) *types.Var {
+	pkg := pass.Pkg
+	if i := strings.Index(name, "."); i >= 0 {
+		if pkgName, ok := pass.TypesInfo.Scopes[file].Lookup(name[:i]).(*types.PkgName); ok {
+			pkg = pkgName.Imported()
+		} else if name[:i] != pass.Pkg.Name() {
+			return nil
 		}
+		name = name[i+1:]
+	}
+	v, ok := pkg.Scope().Lookup(name).(*types.Var)
+	if !ok || isMutexType(v.Type()) {
+		return nil
+	}
+	// pass 与 prog 是分别加载的，按包路径找到 prog 中的变量
+	progPkg := analyzer.prog.ImportedPackage(pkg.Path())
+	if progPkg == nil {
+		return nil
+	}
+	if g := progPkg.Var(name); g != nil {
+		return g.Object().(*types.Var)
 	}
 	return nil
 }
 
+// isNestedPath name 是否是嵌套路径（ stats.hits items[*].x ），而不是 pkg.name
+func isNestedPath(pass *analysis.Pass, file *ast.File, name string) bool {
+	if strings.Contains(name, "[") {
+		return true
+	}
+	parts := strings.Split(name, ".")
+	switch len(parts) {
+	case 1:
+		return false
+	case 2:
+		if _, ok := pass.TypesInfo.Scopes[file].Lookup(parts[0]).(*types.PkgName); ok || parts[0] == pass.Pkg.Name() {
+			return false
+		}
+	}
+	return true
+}
+
 type VarAnalyzer struct {
 	*BaseAnalyzer
 }
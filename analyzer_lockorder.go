@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// 最多输出的环数量
+const maxLockOrderCycles = 100
+
+// lockOrderEdge 持有 from 时对 to 加锁
+type lockOrderEdge struct {
+	from, to *types.Var
+	witness  string // 调用链
+}
+
+// LockOrderAnalyzer 检查不同 mutex 加锁顺序不一致导致的死锁
+type LockOrderAnalyzer struct {
+	cg          *callgraph.Graph
+	prog        *ssa.Program
+	locks       *lockAnalysis
+	mutexes     map[*types.Var]bool
+	pkgs        map[string]bool
+	edges       map[*types.Var]map[*types.Var]*lockOrderEdge
+	below       map[*callgraph.Node]map[*types.Var][]*callgraph.Node
+	PrintsOrder sort.StringSlice
+}
+
+func NewLockOrderAnalyzer(cg *callgraph.Graph, prog *ssa.Program, analyzers ...*BaseAnalyzer) *LockOrderAnalyzer {
+	analyzer := &LockOrderAnalyzer{
+		cg:      cg,
+		prog:    prog,
+		locks:   newLockAnalysis(prog, cg),
+		mutexes: map[*types.Var]bool{},
+		pkgs:    map[string]bool{},
+		edges:   map[*types.Var]map[*types.Var]*lockOrderEdge{},
+		below:   map[*callgraph.Node]map[*types.Var][]*callgraph.Node{},
+	}
+	for _, a := range analyzers {
+		for m := range a.mutexes {
+			analyzer.mutexes[m] = true
+		}
+		for pkg := range a.pkgs {
+			analyzer.pkgs[pkg] = true
+		}
+	}
+	return analyzer
+}
+
+func (analyzer *LockOrderAnalyzer) Analysis() {
+	// 1. 获取 “持有 A 时对 B 加锁” 的边
+	analyzer.step1FindEdges()
+	// 2. 查找加锁顺序图中的环
+	analyzer.step2FindCycles()
+}
+
+func (analyzer *LockOrderAnalyzer) step1FindEdges() {
+	for _, node := range analyzer.cg.Nodes {
+		if !analyzer.inPkgs(node.Func) {
+			continue
+		}
+		st := analyzer.locks.state(node.Func)
+		for _, block := range node.Func.Blocks {
+			for _, instr := range block.Instrs {
+				held := st.before[instr]
+				if len(held) == 0 {
+					continue
+				}
+				c, ok := instr.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				pos := analyzer.prog.Fset.Position(instr.Pos())
+				if nolint(getComment(pos)) {
+					continue
+				}
+				// 加锁顺序按 mutex 字段（不区分实例）统计
+				if to, op := lockOpOf(&c.Call); to.m != nil {
+					if op == opLock || op == opRLock {
+						for from := range held {
+							analyzer.addEdge(from.m, to.m, pos.Filename, pos.Line, []*callgraph.Node{node})
+						}
+					}
+					continue
+				}
+				for _, out := range node.Out {
+					if out.Site != c {
+						continue
+					}
+					for to, paths := range analyzer.locksBelow(out.Callee) {
+						for from := range held {
+							analyzer.addEdge(from.m, to, pos.Filename, pos.Line, append([]*callgraph.Node{node}, paths...))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (analyzer *LockOrderAnalyzer) addEdge(from, to *types.Var, filename string, line int, paths []*callgraph.Node) {
+	if from == to || !analyzer.mutexes[from] || !analyzer.mutexes[to] {
+		return
+	}
+	if _, ok := analyzer.edges[from]; !ok {
+		analyzer.edges[from] = map[*types.Var]*lockOrderEdge{}
+	}
+	// 多个调用链时，取排序最小的，保证输出稳定
+	witness := fmt.Sprintf("%v:%v %v", filename, line, printLockPath(paths, from, to))
+	if e, ok := analyzer.edges[from][to]; ok && e.witness <= witness {
+		return
+	}
+	analyzer.edges[from][to] = &lockOrderEdge{
+		from:    from,
+		to:      to,
+		witness: witness,
+	}
+}
+
+// locksBelow 从 start 开始沿调用图，找到所有会加锁的 mutex ，以及对应的调用链
+func (analyzer *LockOrderAnalyzer) locksBelow(start *callgraph.Node) map[*types.Var][]*callgraph.Node {
+	if r, ok := analyzer.below[start]; ok {
+		return r
+	}
+	type item struct {
+		node  *callgraph.Node
+		paths []*callgraph.Node
+	}
+	r := map[*types.Var][]*callgraph.Node{}
+	seen := map[*callgraph.Node]bool{start: true}
+	queue := []item{{start, []*callgraph.Node{start}}}
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		if !analyzer.inPkgs(it.node.Func) || len(it.paths) >= maxLockPathDepth {
+			continue
+		}
+		for m := range analyzer.mutexes {
+			if _, ok := r[m]; ok {
+				continue
+			}
+			if len(analyzer.locks.lockSites(it.node.Func, m)) > 0 {
+				r[m] = it.paths
+			}
+		}
+		for _, out := range it.node.Out {
+			if _, ok := out.Site.(*ssa.Go); ok {
+				continue
+			}
+			if seen[out.Callee] {
+				continue
+			}
+			seen[out.Callee] = true
+			queue = append(queue, item{out.Callee, append(it.paths[:len(it.paths):len(it.paths)], out.Callee)})
+		}
+	}
+	analyzer.below[start] = r
+	return r
+}
+
+func (analyzer *LockOrderAnalyzer) step2FindCycles() {
+	// 按名字、位置排序，保证输出稳定
+	var nodes []*types.Var
+	for m := range analyzer.edges {
+		nodes = append(nodes, m)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return mutexDesc(analyzer.prog, nodes[i]) < mutexDesc(analyzer.prog, nodes[j]) })
+	index := map[*types.Var]int{}
+	for i, m := range nodes {
+		index[m] = i
+	}
+
+	// 每个环只从其中序号最小的 mutex 开始查找一次
+	var count int
+	var find func(start *types.Var, cur *types.Var, stack []*types.Var, onStack map[*types.Var]bool)
+	find = func(start *types.Var, cur *types.Var, stack []*types.Var, onStack map[*types.Var]bool) {
+		if count >= maxLockOrderCycles {
+			return
+		}
+		var tos []*types.Var
+		for to := range analyzer.edges[cur] {
+			if i, ok := index[to]; ok && i >= index[start] {
+				tos = append(tos, to)
+			}
+		}
+		sort.Slice(tos, func(i, j int) bool { return index[tos[i]] < index[tos[j]] })
+		for _, to := range tos {
+			if to == start {
+				analyzer.printCycle(append(stack, start))
+				count++
+				continue
+			}
+			if onStack[to] {
+				continue
+			}
+			onStack[to] = true
+			find(start, to, append(stack, to), onStack)
+			delete(onStack, to)
+		}
+	}
+	for _, m := range nodes {
+		find(m, m, []*types.Var{m}, map[*types.Var]bool{m: true})
+	}
+}
+
+func (analyzer *LockOrderAnalyzer) printCycle(cycle []*types.Var) {
+	var names []string
+	for _, m := range cycle {
+		names = append(names, mutexDesc(analyzer.prog, m))
+	}
+	s := fmt.Sprintf("[mutex check] 加锁顺序不一致，可能死锁：%v", strings.Join(names, " -> "))
+	for i := 0; i+1 < len(cycle); i++ {
+		s += "\n\t" + analyzer.edges[cycle[i]][cycle[i+1]].witness
+	}
+	analyzer.PrintsOrder = append(analyzer.PrintsOrder, s)
+}
+
+func (analyzer *LockOrderAnalyzer) inPkgs(fn *ssa.Function) bool {
+	return fn != nil && fn.Pkg != nil && analyzer.pkgs[fn.Pkg.Pkg.Path()]
+}
+
+// mutexDesc mutex 的名字及声明位置
+func mutexDesc(prog *ssa.Program, m *types.Var) string {
+	pos := prog.Fset.Position(m.Pos())
+	return fmt.Sprintf("%v(%v:%v)", m.Name(), filepath.Base(pos.Filename), pos.Line)
+}
@@ -13,43 +13,67 @@ import (
 )
 
 func (analyzer *StructFieldAnalyzer) FindVar(pass *analysis.Pass) {
+	refs := guardRefs(pass)
 	for _, file := range pass.Files {
+		// 所有结构体，包括命名类型、匿名结构体全局变量（如 var state struct{ sync.Mutex; m map[K]V }）、匿名结构体字段
 		ast.Inspect(file, func(node ast.Node) bool {
-			switch t := node.(type) {
-			case *ast.TypeSpec:
-				if structType, ok := t.Type.(*ast.StructType); ok {
-					fields := structType.Fields.List
-					for _, field := range fields {
-						if isMutexType(field.Type) {
-							m := analyzer.getStructFieldByPos(analyzer.prog, pass.Fset.Position(field.Pos()))
+			structType, ok := node.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			// 只包装了 mutex 的类型（如 type Mu struct{ sync.Mutex }），本身当作 mutex
+			if isMutexType(pass.TypesInfo.TypeOf(structType)) {
+				return true
+			}
+			fields := structType.Fields.List
+			for _, field := range fields {
+				if !isMutexType(pass.TypesInfo.TypeOf(field.Type)) {
+					if ignored(field.Doc, field.Comment) {
+						continue
+					}
+					// 字段注释、 tag 指明锁它的 mutex ：同一结构体的字段，或者全局变量
+					for _, n := range guardNames(pass.Fset, field) {
+						analyzer.addGuardedBy(pass, structType, field, n)
+					}
+					continue
+				}
+				m := analyzer.getStructFieldVar(pass, field)
+				if m == nil {
+					continue
+				}
 
-							comment := ""
-							if field.Comment != nil {
-								comment = strings.ReplaceAll(field.Comment.Text(), " ", "")
-								comment = strings.ReplaceAll(comment, "\n", "")
-							}
-							if comment == "" {
-								pos := pass.Fset.Position(field.Pos())
-								fmt.Printf("[mutex check] %v:%v mutex 变量没有注释，指明它要锁的变量\n", pos.Filename, pos.Line)
-								continue
-							}
-							if nolint(comment) {
-								continue
-							}
-							mutexFiled := field
-							varNames := strings.Split(comment, ",")
-							for _, name := range varNames {
-								varFiled := analyzer.getStructFieldByName(fields, name)
-								if varFiled == nil {
-									pos := pass.Fset.Position(mutexFiled.Pos())
-									fmt.Printf("[mutex check] %v:%v mutex 变量注释中的变量 %v ，未声明\n", pos.Filename, pos.Line, name)
-									break
-								} else {
-									v := analyzer.getStructFieldByPos(analyzer.prog, pass.Fset.Position(varFiled.Pos()))
-									analyzer.vars[v] = m
-								}
-							}
+				annot := parseMutexAnnotation(pass.Fset, field.Doc, field.Comment)
+				if !annot.found {
+					// 被 guarded_by 引用的，可以不加注释
+					if v, ok := pass.TypesInfo.Defs[fieldIdent(field)].(*types.Var); ok && refs[v] {
+						analyzer.mutexes[m] = true
+						continue
+					}
+					pos := pass.Fset.Position(field.Pos())
+					fmt.Printf("[mutex check] %v:%v mutex 变量没有注释，指明它要锁的变量\n", pos.Filename, pos.Line)
+					continue
+				}
+				if annot.nolint {
+					continue
+				}
+				analyzer.mutexes[m] = true
+				for _, n := range annot.names {
+					name := n.name
+					if strings.ContainsAny(name, ".[") {
+						// 嵌套路径
+						v, path, candidates := analyzer.getStructFieldByPath(pass, structType, name)
+						if v != nil {
+							analyzer.addGuardPath(v, m, path)
+							continue
 						}
+						printUndeclared(pass.Fset, n, candidates)
+						continue
+					}
+					// 结构体的字段，包括嵌入结构体提升的字段
+					if v := analyzer.getStructFieldByName(pass, structType, name); v != nil {
+						analyzer.addGuard(v, m)
+					} else {
+						printUndeclared(pass.Fset, n, fieldNames(pass.TypesInfo.TypeOf(structType)))
 					}
 				}
 			}
@@ -58,8 +82,7 @@ func (analyzer *StructFieldAnalyzer) FindVar(pass *analysis.Pass) {
 	}
 }
 
-func (analyzer *StructFieldAnalyzer) FindCaller(edge *callgraph.Edge, seen map[*callgraph.Node]bool) error {
-	caller := edge.Caller
+func (analyzer *StructFieldAnalyzer) FindCaller(caller *callgraph.Node, seen map[*callgraph.Node]bool) error {
 	if seen[caller] {
 		return nil
 	}
@@ -75,47 +98,49 @@ func (analyzer *StructFieldAnalyzer) FindCaller(edge *callgraph.Edge, seen map[*
 			if instr.Pos() == token.NoPos {
 				continue
 			}
-			if fieldAddr, ok := instr.(*ssa.FieldAddr); ok && fieldAddr.X != nil {
-				if pointerType, ok := fieldAddr.X.Type().Underlying().(*types.Pointer); ok {
-					if structType, ok := pointerType.Elem().Underlying().(*types.Struct); ok {
-						field := structType.Field(fieldAddr.Field)
-						for k := range analyzer.vars {
-							if k == field {
-								pos := analyzer.prog.Fset.Position(instr.Pos())
-								comment := getComment(pos)
-								if nolint(comment) {
-									continue
-								}
-								if _, ok := analyzer.callers[k]; !ok {
-									analyzer.callers[k] = make(map[*callgraph.Node][]token.Position)
-								}
-								analyzer.callers[k][caller] = []token.Position{}
-								break
-							}
-						}
-					}
-				}
+			field, _ := analyzer.fieldAccessOf(instr)
+			if _, ok := analyzer.vars[field]; !ok {
+				continue
+			}
+			pos := analyzer.prog.Fset.Position(instr.Pos())
+			comment := getComment(pos)
+			if nolint(comment) {
+				continue
+			}
+			if _, ok := analyzer.callers[field]; !ok {
+				analyzer.callers[field] = make(map[*callgraph.Node][]token.Position)
 			}
+			analyzer.callers[field][caller] = []token.Position{}
 		}
 	}
 	return nil
 }
 
-func (analyzer *StructFieldAnalyzer) CheckVarLock(prog *ssa.Program, caller *callgraph.Node, mymutex, myvar *types.Var) (poss []token.Position) {
-	var mInstrs []ssa.Instruction
-	var vInstrs []ssa.Instruction
+func (analyzer *StructFieldAnalyzer) CheckVarLock(prog *ssa.Program, caller *callgraph.Node, mymutex, myvar *types.Var) (poss []varAccess) {
 	for _, block := range caller.Func.Blocks {
-		mInstrs = append(mInstrs, analyzer.findInstrByStructFieldCall(block, mymutex)...)
-		vInstrs = append(vInstrs, analyzer.findInstrByStructField(block, myvar)...)
-	}
-	for _, vInstr := range vInstrs {
-		vPos := prog.Fset.Position(vInstr.Pos())
-		comment := getComment(vPos)
-		if nolint(comment) {
-			continue
-		}
-		if !checkMutexLock(prog, mInstrs, vPos) {
-			poss = append(poss, caller.Func.Prog.Fset.Position(vInstr.Pos()))
+		for _, vInstr := range analyzer.findInstrByStructField(block, myvar) {
+			vPos := prog.Fset.Position(vInstr.Pos())
+			comment := getComment(vPos)
+			if nolint(comment) {
+				continue
+			}
+			_, x := analyzer.fieldAccessOf(vInstr)
+			fa, _ := vInstr.(*ssa.FieldAddr)
+			write := fa != nil && isAddrWritten(fa)
+			// 要求锁住同一实例的 mutex ；实例无法确定时，任一实例都算
+			key, ok := analyzer.guardKey(myvar, x)
+			if !ok {
+				continue
+			}
+			if !analyzer.locks.isLocked(vInstr, key, write) {
+				rlocked := write && analyzer.locks.isLocked(vInstr, key, false)
+				otherInstance := !rlocked && analyzer.locks.lockedKind(vInstr, lockKey{m: key.m}) != lockNone
+				access := varAccess{Position: vPos, key: key, write: write, rlocked: rlocked, otherInstance: otherInstance, iter: isRangeAccess(vInstr)}
+				if fa != nil && isPromoted(fa) {
+					access.desc = selectorOf(fa)
+				}
+				poss = append(poss, access)
+			}
 		}
 	}
 	return
@@ -133,21 +158,8 @@ func (analyzer *StructFieldAnalyzer) HaveVar(prog *ssa.Program, caller *callgrap
 	return find
 }
 
-func (analyzer *StructFieldAnalyzer) CheckCallLock(prog *ssa.Program, caller *callgraph.Node, mymutex *types.Var, callee *callgraph.Node) bool {
-	var mInstrs []ssa.Instruction
-	for _, block := range caller.Func.Blocks {
-		mInstrs = append(mInstrs, analyzer.findInstrByStructFieldCall(block, mymutex)...)
-	}
-	for _, vPos := range getCalleePostion(prog, caller, callee) {
-		comment := getComment(vPos)
-		if nolint(comment) {
-			continue
-		}
-		if !checkMutexLock(prog, mInstrs, vPos) {
-			return false
-		}
-	}
-	return true
+func (analyzer *StructFieldAnalyzer) CheckCallLock(prog *ssa.Program, caller *callgraph.Node, key lockKey, callee *callgraph.Node, write bool) bool {
+	return analyzer.callLocked(caller, key, callee, write)
 }
 
 func (analyzer *StructFieldAnalyzer) CheckVarReturn(prog *ssa.Program, caller *callgraph.Node, myvar *types.Var) (poss []token.Position) {
@@ -170,102 +182,241 @@ func (analyzer *StructFieldAnalyzer) CheckVarReturn(prog *ssa.Program, caller *c
 	return
 }
 
-func (analyzer *StructFieldAnalyzer) getStructFieldByPos(prog *ssa.Program, pos token.Position) *types.Var {
-	for _, pkg := range prog.AllPackages() {
-		for _, member := range pkg.Members {
-			if obj, ok := member.(*ssa.Type); ok {
-				if s, ok := obj.Type().Underlying().(*types.Struct); ok {
-					for i := 0; i < s.NumFields(); i++ {
-						field := s.Field(i)
-						p := prog.Fset.Position(field.Pos())
-						if p == pos {
-							return field
-						}
-					}
-				}
-			}
-		}
+// getStructFieldVar 获取字段声明对应的 types.Var （匿名结构体的字段也能找到）
+func (analyzer *StructFieldAnalyzer) getStructFieldVar(pass *analysis.Pass, field *ast.Field) *types.Var {
+	ident := fieldIdent(field)
+	if ident == nil {
+		return nil
+	}
+	obj := pass.TypesInfo.Defs[ident]
+	if obj == nil {
+		return nil
+	}
+	// pass 与 prog 是分别加载的，按位置找到 prog 中的字段
+	return analyzer.getStructFieldByPos(analyzer.prog, pass.Fset.Position(obj.Pos()))
+}
+
+// fieldIdent 字段在 Defs 中的标识符：第一个字段名；嵌入字段记录在类型名上
+func fieldIdent(field *ast.Field) *ast.Ident {
+	if len(field.Names) > 0 {
+		return field.Names[0]
+	}
+	expr := field.Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	// 嵌入泛型类型，如 Box[int]
+	switch index := expr.(type) {
+	case *ast.IndexExpr:
+		expr = index.X
+	case *ast.IndexListExpr:
+		expr = index.X
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr
+	case *ast.SelectorExpr:
+		return expr.Sel
 	}
 	return nil
 }
 
-func (analyzer *StructFieldAnalyzer) findInstrByStructField(block *ssa.BasicBlock, v *types.Var) (instrs []ssa.Instruction) {
-	for _, instr := range block.Instrs {
-		if fieldAddr, ok := instr.(*ssa.FieldAddr); ok && fieldAddr.X != nil {
-			if pointerType, ok := fieldAddr.X.Type().Underlying().(*types.Pointer); ok {
-				if structType, ok := pointerType.Elem().Underlying().(*types.Struct); ok {
-					field := structType.Field(fieldAddr.Field)
-					if field == v {
-						instrs = append(instrs, instr)
-					}
-				}
-			}
+// fieldNames 结构体（或其指针）的字段名，用于拼写建议
+func fieldNames(t types.Type) (names []string) {
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if st, ok := t.Underlying().(*types.Struct); ok {
+		for i := 0; i < st.NumFields(); i++ {
+			names = append(names, st.Field(i).Name())
 		}
 	}
 	return
 }
 
-func (analyzer *StructFieldAnalyzer) findInstrByStructFieldCall(block *ssa.BasicBlock, v *types.Var) (instrs []ssa.Instruction) {
-	for _, instr := range block.Instrs {
-		var c ssa.CallCommon
-		switch v := instr.(type) {
-		case *ssa.Call:
-			c = v.Call
-		case *ssa.Defer:
-			c = v.Call
+// addGuardedBy 字段的注释 // guarded_by: name 或 tag guarded_by:"name" ，先在结构体 structType 中查找 mutex
+func (analyzer *StructFieldAnalyzer) addGuardedBy(pass *analysis.Pass, structType *ast.StructType, field *ast.Field, n annotName) {
+	var m *types.Var
+	if sibling := lookupFieldGuard(pass.TypesInfo.TypeOf(structType), pass.Pkg, n.name); sibling != nil {
+		m = analyzer.getStructFieldByPos(analyzer.prog, pass.Fset.Position(sibling.Pos()))
+	} else {
+		m = analyzer.resolveGuard(pass, n)
+	}
+	if m == nil {
+		pos := pass.Fset.Position(n.pos)
+		fmt.Printf("[mutex check] %v:%v guarded_by 中的 mutex %v ，未声明\n", pos.Filename, pos.Line, n.name)
+		return
+	}
+	if v := analyzer.getStructFieldVar(pass, field); v != nil {
+		analyzer.addGuard(v, m)
+	}
+}
+
+// getStructFieldByName 按名字获取结构体的字段（包括嵌入结构体提升的字段），返回 prog 中对应的变量
+func (analyzer *StructFieldAnalyzer) getStructFieldByName(pass *analysis.Pass, structType *ast.StructType, name string) *types.Var {
+	t := pass.TypesInfo.TypeOf(structType)
+	if t == nil {
+		return nil
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, pass.Pkg, name)
+	v, ok := obj.(*types.Var)
+	if !ok || !v.IsField() {
+		return nil
+	}
+	return analyzer.getStructFieldByPos(analyzer.prog, pass.Fset.Position(v.Pos()))
+}
+
+// getStructFieldByPath 解析嵌套路径，如 stats.hits （结构体字段的字段）、 items[*].state （ slice map 每个元素的字段）。
+// 返回最后的字段，以及经过的字段；找不到时，返回无法解析的那一段可用的路径，用于拼写建议
+func (analyzer *StructFieldAnalyzer) getStructFieldByPath(pass *analysis.Pass, structType *ast.StructType, name string) (*types.Var, []*types.Var, []string) {
+	t := pass.TypesInfo.TypeOf(structType)
+	var fields []*types.Var
+	prefix := ""
+	for _, seg := range strings.Split(name, ".") {
+		elem := strings.HasSuffix(seg, "[*]")
+		seg = strings.TrimSuffix(seg, "[*]")
+		if t == nil || seg == "" {
+			return nil, nil, nil
+		}
+		obj, _, _ := types.LookupFieldOrMethod(t, true, pass.Pkg, seg)
+		v, ok := obj.(*types.Var)
+		if !ok {
+			var candidates []string
+			for _, c := range fieldNames(t) {
+				candidates = append(candidates, prefix+c)
+			}
+			return nil, nil, candidates
 		}
-		if c.Pos().IsValid() {
-			if c.Signature().Recv() != nil && c.Args != nil {
-				arg := c.Args[0]
-				switch arg2 := arg.(type) {
-				case *ssa.UnOp:
-					arg = arg2.X
+		field := analyzer.getStructFieldByPos(analyzer.prog, pass.Fset.Position(v.Pos()))
+		if field == nil {
+			return nil, nil, nil
+		}
+		fields = append(fields, field)
+		prefix += seg
+		if elem {
+			prefix += "[*]"
+		}
+		prefix += "."
+		t = v.Type()
+		if elem {
+			t = elemType(t)
+		}
+	}
+	return fields[len(fields)-1], fields[:len(fields)-1], nil
+}
+
+// elemType slice array map （或其指针）的元素类型
+func elemType(t types.Type) types.Type {
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	switch t := t.Underlying().(type) {
+	case *types.Slice:
+		return t.Elem()
+	case *types.Array:
+		return t.Elem()
+	case *types.Map:
+		return t.Elem()
+	}
+	return nil
+}
+
+// getStructFieldByPos 按声明位置查找结构体字段
+func (analyzer *StructFieldAnalyzer) getStructFieldByPos(prog *ssa.Program, pos token.Position) *types.Var {
+	if analyzer.fields == nil {
+		analyzer.fields = map[token.Position]*types.Var{}
+		seen := map[types.Type]bool{}
+		for _, pkg := range prog.AllPackages() {
+			for _, member := range pkg.Members {
+				switch member := member.(type) {
+				case *ssa.Type:
+					analyzer.collectFields(member.Type(), seen)
+				case *ssa.Global:
+					analyzer.collectFields(member.Type(), seen)
 				}
-				if fieldAddr, ok := arg.(*ssa.FieldAddr); ok && fieldAddr.X != nil {
-					if pointerType, ok := fieldAddr.X.Type().Underlying().(*types.Pointer); ok {
-						if structType, ok := pointerType.Elem().Underlying().(*types.Struct); ok {
-							field := structType.Field(fieldAddr.Field)
-							if field == v || v == nil {
-								instrs = append(instrs, instr)
-							}
-						}
+			}
+		}
+		// 函数内声明的类型
+		for fn := range analyzer.cg.Nodes {
+			if fn == nil {
+				continue
+			}
+			for _, block := range fn.Blocks {
+				for _, instr := range block.Instrs {
+					switch instr := instr.(type) {
+					case *ssa.FieldAddr:
+						analyzer.collectFields(instr.X.Type(), seen)
+					case *ssa.Field:
+						analyzer.collectFields(instr.X.Type(), seen)
 					}
 				}
 			}
 		}
 	}
+	return analyzer.fields[pos]
+}
+
+// collectFields 记录类型 t 中（包括嵌套的匿名结构体）所有字段的位置
+func (analyzer *StructFieldAnalyzer) collectFields(t types.Type, seen map[types.Type]bool) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.Named:
+		analyzer.collectFields(t.Underlying(), seen)
+	case *types.Pointer:
+		analyzer.collectFields(t.Elem(), seen)
+	case *types.Slice:
+		analyzer.collectFields(t.Elem(), seen)
+	case *types.Array:
+		analyzer.collectFields(t.Elem(), seen)
+	case *types.Map:
+		analyzer.collectFields(t.Key(), seen)
+		analyzer.collectFields(t.Elem(), seen)
+	case *types.Chan:
+		analyzer.collectFields(t.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			field := t.Field(i)
+			analyzer.fields[analyzer.prog.Fset.Position(field.Pos())] = field
+			analyzer.collectFields(field.Type(), seen)
+		}
+	}
+}
+
+func (analyzer *StructFieldAnalyzer) findInstrByStructField(block *ssa.BasicBlock, v *types.Var) (instrs []ssa.Instruction) {
+	for _, instr := range block.Instrs {
+		if field, _ := analyzer.fieldAccessOf(instr); field == v {
+			instrs = append(instrs, instr)
+		}
+	}
 	return
 }
 
-func (analyzer *StructFieldAnalyzer) getStructFieldByName(fields []*ast.Field, name string) *ast.Field {
-	for _, field := range fields {
-		var n string
-		if len(field.Names) > 0 {
-			n = field.Names[0].Name
-		} else if v, ok := field.Type.(*ast.SelectorExpr); ok {
-			n = v.Sel.Name
-		} else if v, ok := field.Type.(*ast.StarExpr); ok {
-			if v2, ok := v.X.(*ast.Ident); ok {
-				n = v2.Name
-			} else if v2, ok := v.X.(*ast.SelectorExpr); ok {
-				n = v2.Sel.Name
-			} else {
-				panic("getStructFieldByName, here #1")
+// fieldAccessOf 指令对结构体字段的访问，返回字段及其所在的结构体实例：取字段地址 FieldAddr ，
+// 或者从结构体值中取字段 Field （如 map 元素 s.items[k].state ）。普通字段通过值访问的是副本，只有嵌套路径上的字段检查 Field
+func (analyzer *StructFieldAnalyzer) fieldAccessOf(instr ssa.Instruction) (*types.Var, ssa.Value) {
+	switch instr := instr.(type) {
+	case *ssa.FieldAddr:
+		if instr.X != nil {
+			if field := structFieldOf(instr); field != nil {
+				return field, instr.X
 			}
-		} else if v, ok := field.Type.(*ast.Ident); ok {
-			n = v.Name
-		} else {
-			panic("getStructFieldByName, here #2")
 		}
-		if n == name {
-			return field
+	case *ssa.Field:
+		if st, ok := instr.X.Type().Underlying().(*types.Struct); ok {
+			if field := st.Field(instr.Field); len(analyzer.paths[field]) > 0 {
+				return field, instr.X
+			}
 		}
 	}
-	return nil
+	return nil, nil
 }
 
 type StructFieldAnalyzer struct {
 	*BaseAnalyzer
+	fields map[token.Position]*types.Var // 按声明位置索引的结构体字段
 }
 
 func NewStructFieldAnalyzer(path string, cg *callgraph.Graph, prog *ssa.Program) *StructFieldAnalyzer {
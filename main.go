@@ -3,17 +3,29 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"sort"
 )
 
 var path string
 var buildFlag string
+var lockTypesFlag string
+var lockedPatternFlag string
 
 func main() {
 	flag.StringVar(&path, "path", ".", "package path")
 	flag.StringVar(&buildFlag, "buildflag", "--tags=", "build flag")
+	flag.StringVar(&lockTypesFlag, "locktypes", "", "extra lock types, e.g. example.com/spin.Lock:Acquire,Release;example.com/rw.RWLock:Lock,Unlock,RLock,RUnlock")
+	flag.StringVar(&lockedPatternFlag, "lockedpattern", "", "functions matching this regexp (e.g. Locked$) require the caller to hold the receiver's mutex")
 	flag.Parse()
 
+	if err := parseLockTypes(lockTypesFlag); err != nil {
+		usageExit(err)
+	}
+	if err := parseLockedPattern(lockedPatternFlag); err != nil {
+		usageExit(err)
+	}
+
 	cg, prog, err := doCallgraph("vta", false, getAllPackageName(path))
 	if err != nil {
 		panic(err)
@@ -25,19 +37,25 @@ func main() {
 	analyzer2 := NewStructFieldAnalyzer(path, cg, prog)
 	analyzer2.Analysis()
 
-	m := map[string]bool{}
-	s := append(analyzer1.PrintsCall, analyzer2.PrintsCall...)
-	sort.Sort(s)
-	for _, v := range s {
-		if _, ok := m[v]; ok {
-			continue
-		}
-		m[v] = true
-		fmt.Println(v)
-	}
+	analyzer3 := NewLockOrderAnalyzer(cg, prog, analyzer1.BaseAnalyzer, analyzer2.BaseAnalyzer)
+	analyzer3.Analysis()
+
+	printAll(append(analyzer1.PrintsCall, analyzer2.PrintsCall...))
+	printAll(append(analyzer1.PrintsReturn, analyzer2.PrintsReturn...))
+	printAll(append(analyzer1.PrintsLock, analyzer2.PrintsLock...))
+	printAll(analyzer3.PrintsOrder)
+}
+
+// usageExit 参数格式错误：输出错误及用法，退出
+func usageExit(err error) {
+	fmt.Fprintln(flag.CommandLine.Output(), err)
+	flag.Usage()
+	os.Exit(2)
+}
 
-	m = map[string]bool{}
-	s = append(analyzer1.PrintsReturn, analyzer2.PrintsReturn...)
+// printAll 排序、去重后输出
+func printAll(s sort.StringSlice) {
+	m := map[string]bool{}
 	sort.Sort(s)
 	for _, v := range s {
 		if _, ok := m[v]; ok {
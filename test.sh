@@ -6,4 +6,6 @@ go build
 mv -f go_mutex_check test
 cd test
 ./go_mutex_check --path=.
+# test31.go 的命名约定需要 --lockedpattern
+./go_mutex_check --path=. --lockedpattern='Locked31$'
 cd ..
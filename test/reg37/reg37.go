@@ -0,0 +1,8 @@
+package reg37
+
+import "sync"
+
+// Mu 锁注册表，其他包的变量也可以由它锁住
+var Mu sync.Mutex // Plugins
+
+var Plugins = map[string]int{}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+var m11 sync.Mutex // a11
+var a11 int
+
+// 分支中加锁，汇合后并不一定持有锁
+func f11Branch(x bool) {
+	if x {
+		m11.Lock()
+	}
+	fmt.Print(a11)
+	if x {
+		m11.Unlock()
+	}
+}
+
+// 提前返回的分支上已经解锁
+func f11Return(x bool) {
+	m11.Lock()
+	if x {
+		m11.Unlock()
+		return
+	}
+	a11++
+	m11.Unlock()
+}
+
+// 解锁之后的访问
+func f11After() {
+	m11.Lock()
+	a11++
+	m11.Unlock()
+	fmt.Print(a11)
+}
+
+// 循环中加锁
+func f11Loop(n int) {
+	for i := 0; i < n; i++ {
+		m11.Lock()
+		a11++
+		m11.Unlock()
+	}
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+var m12 sync.RWMutex // a12, b12, c12
+var a12 int
+var b12 = map[int]int{}
+var c12 []int
+
+// 读操作，持有 RLock 即可
+func f12Read() {
+	m12.RLock()
+	defer m12.RUnlock()
+	fmt.Print(a12, b12[1], c12[0])
+}
+
+// 写操作，只持有 RLock
+func f12Write() {
+	m12.RLock()
+	defer m12.RUnlock()
+	a12++
+	b12[1] = 2
+	c12[0] = 3
+	c12 = append(c12, 4)
+	delete(b12, 1)
+}
+
+func f12WriteInner() {
+	b12[2] = 3
+}
+
+// 上层调用只持有 RLock
+func f12WriteOuter() {
+	m12.RLock()
+	f12WriteInner()
+	m12.RUnlock()
+}
+
+type T12 struct {
+	mu sync.RWMutex // m, n
+	m  map[int]int
+	n  struct{ x int }
+}
+
+func (t *T12) write() {
+	t.mu.RLock()
+	t.m[1] = 1
+	t.n.x = 2
+	t.mu.RUnlock()
+}
+
+func (t *T12) read() {
+	t.mu.RLock()
+	fmt.Print(t.m[1], t.n.x)
+	t.mu.RUnlock()
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+type T13 struct {
+	mu sync.Mutex // a
+	a  int
+}
+
+func (t *T13) lock() {
+	t.mu.Lock()
+}
+
+func (t *T13) unlock() {
+	t.mu.Unlock()
+}
+
+// 通过辅助函数加锁、解锁
+func (t *T13) F1() {
+	t.lock()
+	t.a++
+	t.unlock()
+}
+
+// 通过辅助函数解锁之后的访问
+func (t *T13) F2() {
+	t.lock()
+	t.unlock()
+	fmt.Print(t.a)
+}
+
+// 辅助函数内 defer 解锁，返回时不再持有锁
+func (t *T13) lockAndDefer() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+}
+
+func (t *T13) F3() {
+	t.lockAndDefer()
+	fmt.Print(t.a)
+}
+
+// 上层函数通过辅助函数加锁
+func (t *T13) inc() {
+	t.a++
+}
+
+func (t *T13) F4() {
+	t.lock()
+	t.inc()
+	t.unlock()
+}
+
+var m13 sync.Mutex // a13
+var a13 int
+
+func lock13() {
+	m13.Lock()
+}
+
+func unlockAndNotify13(ch chan int) {
+	m13.Unlock()
+	ch <- 1
+}
+
+func F13(ch chan int) {
+	lock13()
+	a13++
+	unlockAndNotify13(ch)
+	a13++
+}
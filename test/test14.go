@@ -0,0 +1,42 @@
+package test
+
+import "sync"
+
+type T14 struct {
+	mu sync.Mutex // a
+	a  int
+}
+
+func (t *T14) get() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.a
+}
+
+func (t *T14) getWrap() int {
+	return t.get()
+}
+
+// 持有锁时，通过调用链再次加锁
+func (t *T14) Inc() {
+	t.mu.Lock()
+	t.a = t.getWrap() + 1
+	t.mu.Unlock()
+}
+
+// 持有锁时，直接再次加锁
+func (t *T14) Twice() {
+	t.mu.Lock()
+	t.mu.Lock()
+	t.a++
+	t.mu.Unlock()
+	t.mu.Unlock()
+}
+
+// 先解锁再调用，不报错
+func (t *T14) Ok() {
+	t.mu.Lock()
+	t.a++
+	t.mu.Unlock()
+	t.get()
+}
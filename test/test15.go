@@ -0,0 +1,45 @@
+package test
+
+import "sync"
+
+var m15a sync.Mutex // a15
+var a15 int
+
+var m15b sync.Mutex // b15
+var b15 int
+
+func f15AB() {
+	m15a.Lock()
+	m15b.Lock()
+	a15, b15 = b15, a15
+	m15b.Unlock()
+	m15a.Unlock()
+}
+
+func lockB15() {
+	m15b.Lock()
+	b15++
+	m15b.Unlock()
+}
+
+// 持有 m15b 时，通过调用链对 m15a 加锁
+func f15BA() {
+	m15b.Lock()
+	b15++
+	incA15()
+	m15b.Unlock()
+}
+
+func incA15() {
+	m15a.Lock()
+	a15++
+	m15a.Unlock()
+}
+
+// 持有 m15a 时调用 lockB15 ，与上面的顺序一致，不会单独成环
+func f15A() {
+	m15a.Lock()
+	a15++
+	lockB15()
+	m15a.Unlock()
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"errors"
+	"sync"
+)
+
+type T16 struct {
+	mu sync.Mutex // a
+	a  int
+}
+
+// 出错分支提前返回，没有解锁
+func (t *T16) Set(v int) error {
+	t.mu.Lock()
+	if v < 0 {
+		return errors.New("invalid")
+	}
+	t.a = v
+	t.mu.Unlock()
+	return nil
+}
+
+// defer 解锁，不报错
+func (t *T16) Get() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.a
+}
+
+// 所有路径都持有，视为有意加锁，不报错
+func (t *T16) lock() {
+	t.mu.Lock()
+}
+
+func (t *T16) Inc() {
+	t.lock()
+	t.a++
+	t.mu.Unlock()
+}
+
+// 出错分支在 defer 注册之前返回，没有解锁
+func (t *T16) Reset(v int) error {
+	t.mu.Lock()
+	if v < 0 {
+		return errors.New("invalid")
+	}
+	defer t.mu.Unlock()
+	t.a = 0
+	return nil
+}
+
+// 只在加锁的分支注册 defer ，不报错
+func (t *T16) Maybe(v int) {
+	if v > 0 {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.a = v
+	}
+}
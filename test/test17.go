@@ -0,0 +1,116 @@
+package test
+
+import "sync"
+
+var m17 sync.RWMutex // a17
+var a17 int
+
+// 重复解锁
+func f17Twice() {
+	m17.Lock()
+	a17++
+	m17.Unlock()
+	m17.Unlock()
+}
+
+// RLock 后调用 Unlock
+func f17RLockUnlock() int {
+	m17.RLock()
+	defer m17.Unlock()
+	return a17
+}
+
+// Lock 后调用 RUnlock
+func f17LockRUnlock() {
+	m17.Lock()
+	a17++
+	m17.RUnlock()
+}
+
+// 有路径没有加锁就解锁
+func f17Branch(x bool) {
+	if x {
+		m17.Lock()
+	}
+	m17.Unlock()
+}
+
+// defer 解锁前已经解锁
+func f17Defer() {
+	m17.Lock()
+	defer m17.Unlock()
+	a17++
+	m17.Unlock()
+}
+
+type T17 struct {
+	mu sync.Mutex // a
+	a  int
+}
+
+// 解锁调用者持有的锁，不报错
+func (t *T17) unlockAndNotify(ch chan int) {
+	t.mu.Unlock()
+	ch <- 1
+}
+
+// 结构体字段 mutex 重复解锁
+func (t *T17) Twice() {
+	t.mu.Lock()
+	t.a++
+	t.mu.Unlock()
+	t.mu.Unlock()
+}
+
+func unlock17() {
+	m17.Unlock()
+}
+
+func lock17() {
+	m17.Lock()
+}
+
+// 调用的函数中重复解锁
+func f17Helper() {
+	m17.Lock()
+	a17++
+	m17.Unlock()
+	unlock17()
+}
+
+// 有路径没有加锁，就调用函数解锁
+func f17HelperBranch(x bool) {
+	if x {
+		lock17()
+	}
+	unlock17()
+}
+
+// 加锁后调用函数解锁，不报错
+func f17HelperOK() {
+	lock17()
+	a17++
+	unlock17()
+}
+
+// 先注册 defer 的分支返回，不是重复解锁
+func f17DeferBranch(x bool) {
+	m17.Lock()
+	if x {
+		m17.Unlock()
+		return
+	}
+	defer m17.Unlock()
+	a17++
+}
+
+// 只在一个分支注册 defer ，另一个分支直接解锁，汇合后返回，不是重复解锁
+func f17DeferOneBranch(x bool) {
+	m17.Lock()
+	a17--
+	if x {
+		defer m17.Unlock()
+	} else {
+		m17.Unlock()
+	}
+}
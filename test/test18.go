@@ -0,0 +1,81 @@
+package test
+
+import "sync"
+
+type T18 struct {
+	mu  sync.Mutex // n18
+	n18 int
+}
+
+var t18s = map[int]*T18{}
+
+func getT18(id int) *T18 {
+	return t18s[id]
+}
+
+// 锁住 a.mu ，却修改 b.n18
+func Copy18(a, b *T18) {
+	a.mu.Lock()
+	b.n18 = a.n18
+	a.mu.Unlock()
+}
+
+// 同一实例，不报错
+func (t *T18) Inc() {
+	t.mu.Lock()
+	t.n18++
+	t.mu.Unlock()
+}
+
+// 通过调用加锁同一实例，不报错
+func (t *T18) lock() {
+	t.mu.Lock()
+}
+
+func (t *T18) Dec() {
+	t.lock()
+	t.n18--
+	t.mu.Unlock()
+}
+
+// 无法确定是否同一实例（函数返回值），不报错
+func Reset18(id int) {
+	t := getT18(id)
+	getT18(id).mu.Lock()
+	t.n18 = 0
+	getT18(id).mu.Unlock()
+}
+
+// 锁住 a.mu 时对 b.mu 加锁，不是重复加锁
+func Swap18(a, b *T18) {
+	a.mu.Lock()
+	b.mu.Lock()
+	a.n18, b.n18 = b.n18, a.n18
+	b.mu.Unlock()
+	a.mu.Unlock()
+}
+
+// 持有 a.mu 时调用 b 的方法加锁，不是重复加锁
+func Merge18(a, b *T18) {
+	a.mu.Lock()
+	b.Inc()
+	a.mu.Unlock()
+}
+
+func (t *T18) add18() {
+	t.n18++
+}
+
+// 锁住 a.mu ，调用方法修改 b.n18
+func Add18(a, b *T18) {
+	a.mu.Lock()
+	b.add18()
+	a.mu.Unlock()
+}
+
+// 锁住同一实例后调用方法，不报错
+func (t *T18) Add() {
+	t.mu.Lock()
+	t.add18()
+	t.mu.Unlock()
+}
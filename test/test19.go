@@ -0,0 +1,98 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+type T19 struct {
+	mu  sync.Mutex // n19
+	n19 int
+	cb  func()
+}
+
+// go 启动的匿名函数，不持有锁
+func (t *T19) Go() {
+	t.mu.Lock()
+	go func() {
+		t.n19++
+	}()
+	t.mu.Unlock()
+}
+
+func (t *T19) inc() {
+	t.n19++
+}
+
+// go 启动的方法，不持有锁
+func (t *T19) GoMethod() {
+	t.mu.Lock()
+	go t.inc()
+	t.mu.Unlock()
+}
+
+// defer 在解锁之后才执行
+func (t *T19) DeferAfterUnlock() {
+	t.mu.Lock()
+	defer func() {
+		fmt.Print(t.n19)
+	}()
+	t.mu.Unlock()
+}
+
+// defer 在 defer 解锁之前执行，不报错
+func (t *T19) DeferBeforeUnlock() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer func() {
+		fmt.Print(t.n19)
+	}()
+}
+
+// 保存起来的回调，调用时不持有锁
+func (t *T19) Store() {
+	t.mu.Lock()
+	t.cb = func() {
+		t.n19 = 0
+	}
+	t.mu.Unlock()
+}
+
+func (t *T19) Run() {
+	t.cb()
+}
+
+// 加锁范围内直接调用，不报错
+func (t *T19) Call() {
+	t.mu.Lock()
+	func() {
+		t.n19++
+	}()
+	t.mu.Unlock()
+}
+
+// 提前返回时还没有注册 defer ，不报错
+func (t *T19) DeferAfterEarlyReturn(c bool) {
+	if c {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer func() {
+		t.n19++
+	}()
+}
+
+func (t *T19) flush() {
+	fmt.Print(t.n19)
+}
+
+// 提前返回时还没有注册 defer ，defer 调用的方法不报错
+func (t *T19) DeferHelperAfterEarlyReturn(c bool) {
+	if c {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.flush()
+}
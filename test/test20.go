@@ -0,0 +1,79 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+var m20 sync.Mutex // users20
+var users20 = map[int]string{}
+
+func print20(users map[int]string) {
+	for id, name := range users {
+		fmt.Println(id, name)
+	}
+}
+
+func set20(users map[int]string, id int) {
+	users[id] = ""
+}
+
+func setWrap20(users map[int]string) {
+	set20(users, 1)
+}
+
+// 加锁时取出，解锁后传给其他函数
+func Dump20() {
+	m20.Lock()
+	users := users20
+	m20.Unlock()
+	print20(users)
+}
+
+// 经过多层调用后写入
+func Reset20() {
+	m20.Lock()
+	users := users20
+	m20.Unlock()
+	setWrap20(users)
+}
+
+// 加锁范围内调用，不报错
+func Dump20Locked() {
+	m20.Lock()
+	print20(users20)
+	m20.Unlock()
+}
+
+// 被调函数自己加锁，不报错
+func lockAndSet20(users map[int]string) {
+	m20.Lock()
+	users[2] = ""
+	m20.Unlock()
+}
+
+func Set20() {
+	m20.Lock()
+	users := users20
+	m20.Unlock()
+	lockAndSet20(users)
+}
+
+type T20 struct {
+	mu    sync.Mutex // items
+	items []int
+}
+
+func sum20(items []int) (n int) {
+	for _, v := range items {
+		n += v
+	}
+	return
+}
+
+// 结构体字段，go 启动的函数不持有锁
+func (t *T20) Sum() {
+	t.mu.Lock()
+	go sum20(t.items)
+	t.mu.Unlock()
+}
@@ -0,0 +1,59 @@
+package test
+
+import "sync"
+
+var m21 sync.RWMutex // cache21, ptr21
+var cache21 = map[string]int{}
+var ptr21 = &T21{}
+
+type T21 struct {
+	n int
+}
+
+type holder21 struct {
+	cache map[string]int
+}
+
+// 取出后解锁，再写入
+func Set21(k string, v int) {
+	m21.Lock()
+	m := cache21
+	m21.Unlock()
+	m[k] = v
+}
+
+// 经过 phi 传递
+func Get21(k string, other map[string]int) int {
+	m21.RLock()
+	m := other
+	if other == nil {
+		m = cache21
+	}
+	m21.RUnlock()
+	return m[k]
+}
+
+// 经过结构体拷贝传递
+func Holder21(k string) int {
+	m21.RLock()
+	h := holder21{cache: cache21}
+	m21.RUnlock()
+	return h.cache[k]
+}
+
+// 指针解引用
+func Inc21() {
+	m21.Lock()
+	p := ptr21
+	m21.Unlock()
+	p.n++
+}
+
+// 解锁前使用，不报错
+func Len21() int {
+	m21.RLock()
+	m := cache21
+	n := len(m)
+	m21.RUnlock()
+	return n
+}
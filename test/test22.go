@@ -0,0 +1,91 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+var m22 sync.RWMutex // cache22, list22
+var cache22 = map[string]*T22{}
+var list22 []*T22
+
+type T22 struct {
+	mu    sync.Mutex // items
+	items map[int]string
+	n     int
+}
+
+// 没有加锁遍历 map
+func Dump22() {
+	for k, v := range cache22 {
+		fmt.Println(k, v)
+	}
+}
+
+// 没有加锁遍历 slice
+func Sum22() (n int) {
+	for _, v := range list22 {
+		n += v.n
+	}
+	return
+}
+
+// 加锁遍历，不报错
+func Count22() (n int) {
+	m22.RLock()
+	for range cache22 {
+		n++
+	}
+	m22.RUnlock()
+	return
+}
+
+// 没有加锁遍历结构体字段
+func (t *T22) Dump() {
+	for k, v := range t.items {
+		fmt.Println(k, v)
+	}
+}
+
+// 返回 map 中的元素（指针）
+func Get22(k string) *T22 {
+	m22.RLock()
+	defer m22.RUnlock()
+	return cache22[k]
+}
+
+// 返回 slice 中元素的地址
+func First22() **T22 {
+	m22.RLock()
+	defer m22.RUnlock()
+	return &list22[0]
+}
+
+// 返回遍历得到的元素
+func Find22(n int) *T22 {
+	m22.RLock()
+	defer m22.RUnlock()
+	for _, v := range cache22 {
+		if v.n == n {
+			return v
+		}
+	}
+	return nil
+}
+
+// 返回元素的值拷贝，不报错
+func (t *T22) Get(k int) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.items[k]
+}
+
+// 取出后解锁，再遍历
+func Dump22Copy() {
+	m22.RLock()
+	c := cache22
+	m22.RUnlock()
+	for k := range c {
+		fmt.Println(k)
+	}
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 匿名结构体全局变量，嵌入 mutex
+var state23 struct {
+	sync.Mutex // users23
+	users23    map[int]string
+}
+
+// 匿名结构体全局变量，命名的 mutex 字段
+var stats23 struct {
+	mu     sync.RWMutex // hits23
+	hits23 int
+}
+
+func Add23(id int, name string) {
+	state23.Lock()
+	state23.users23[id] = name
+	state23.Unlock()
+}
+
+func Del23(id int) {
+	delete(state23.users23, id)
+}
+
+func Hit23() {
+	stats23.mu.Lock()
+	stats23.hits23++
+	stats23.mu.Unlock()
+}
+
+func Hits23() int {
+	return stats23.hits23
+}
+
+// 匿名结构体字段
+type T23 struct {
+	name  string
+	cache struct {
+		mu     sync.Mutex // data23
+		data23 map[string]int
+	}
+}
+
+func (t *T23) Set(k string, v int) {
+	t.cache.mu.Lock()
+	t.cache.data23[k] = v
+	t.cache.mu.Unlock()
+}
+
+func (t *T23) Print(k string) {
+	fmt.Println(t.cache.data23[k])
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"fmt"
+	gosync "sync"
+)
+
+// import 别名
+var m24a gosync.Mutex // a24
+
+var a24 int
+
+// 类型别名
+type Mutex24 = gosync.RWMutex
+
+var m24b Mutex24 // b24
+
+var b24 int
+
+// 包装类型，函数返回值初始化
+type Mu24 struct {
+	gosync.Mutex
+}
+
+func newMu24() *Mu24 {
+	return &Mu24{}
+}
+
+var m24c = newMu24() // c24
+
+var c24 int
+
+// 包装类型的字段
+type T24 struct {
+	mu  Mu24 // d24
+	d24 int
+}
+
+func F24() {
+	m24a.Lock()
+	a24++
+	m24a.Unlock()
+	m24b.Lock()
+	b24++
+	m24b.Unlock()
+	m24c.Lock()
+	c24++
+	m24c.Unlock()
+}
+
+func G24a() {
+	fmt.Println(a24)
+}
+
+func G24b() {
+	fmt.Println(b24)
+}
+
+func G24c() {
+	fmt.Println(c24)
+}
+
+func (t *T24) Inc() {
+	t.mu.Lock()
+	t.d24++
+	t.mu.Unlock()
+}
+
+func (t *T24) Print() {
+	fmt.Println(t.d24)
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// sync.Locker 接口
+var m25a sync.Locker = &sync.Mutex{} // a25
+
+var a25 int
+
+func F25a() {
+	m25a.Lock()
+	a25++
+	m25a.Unlock()
+}
+
+func G25a() {
+	fmt.Println(a25)
+}
+
+// 自定义锁类型，需要 --locktypes=github.com/fananchong/go_mutex_check/test.Spin25:Acquire,Release
+type Spin25 struct {
+	state int32
+}
+
+func (s *Spin25) Acquire() {
+	for !atomic.CompareAndSwapInt32(&s.state, 0, 1) {
+	}
+}
+
+func (s *Spin25) Release() {
+	atomic.StoreInt32(&s.state, 0)
+}
+
+type T25 struct {
+	mu  Spin25 // b25
+	b25 int
+}
+
+func (t *T25) Inc() {
+	t.mu.Acquire()
+	t.b25++
+	t.mu.Release()
+}
+
+func (t *T25) Print() {
+	fmt.Println(t.b25)
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+type Inner26 struct {
+	mu  sync.Mutex // a26
+	a26 int
+}
+
+// 经嵌入结构体访问 Inner26 的字段
+type Outer26 struct {
+	Inner26
+}
+
+func (o *Outer26) F26a() {
+	o.mu.Lock()
+	o.a26++
+	o.mu.Unlock()
+}
+
+func (o *Outer26) F26b() {
+	o.Inner26.mu.Lock()
+	o.a26++
+	o.Inner26.mu.Unlock()
+}
+
+func (o *Outer26) G26() {
+	fmt.Println(o.a26)
+}
+
+// 外层结构体的 mutex ，锁嵌入结构体提升的字段
+type Base26 struct {
+	b26 map[string]int
+}
+
+type Holder26 struct {
+	mu sync.RWMutex // b26
+	*Base26
+}
+
+func (h *Holder26) F26c() {
+	h.mu.RLock()
+	fmt.Println(h.b26["x"])
+	h.mu.RUnlock()
+}
+
+func (h *Holder26) G26b() {
+	h.b26["x"] = 1
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+type Stats27 struct {
+	hits27  int
+	total27 int
+}
+
+type Item27 struct {
+	state27 int
+	name27  string
+}
+
+// mu 锁 stats 的 hits27 字段，以及 items 每个元素的 state27 字段
+type Server27 struct {
+	mu     sync.Mutex // stats.hits27, items[*].state27, byName[*].state27, byVal[*].state27
+	stats  Stats27
+	items  []Item27
+	byName map[string]*Item27
+	byVal  map[string]Item27
+}
+
+func (s *Server27) F27a() {
+	s.mu.Lock()
+	s.stats.hits27++
+	s.items[0].state27 = 1
+	s.byName["x"].state27 = 1
+	s.mu.Unlock()
+}
+
+func (s *Server27) G27a() {
+	s.stats.hits27++
+}
+
+func (s *Server27) G27b(i int) {
+	fmt.Println(s.items[i].state27)
+}
+
+func (s *Server27) G27c() {
+	s.byName["x"].state27 = 2
+}
+
+// 不在路径上的字段、单独的 Stats27 ，不需要加锁
+func (s *Server27) H27(st *Stats27) {
+	s.stats.total27++
+	s.items[0].name27 = "a"
+	st.hits27++
+}
+
+// map 的值是结构体，读取元素的字段
+func (s *Server27) G27d(k string) int {
+	return s.byVal[k].state27
+}
+
+func (s *Server27) F27b(k string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.byVal[k].state27
+}
+
+// 另一个结构体中的 Item27 由它自己的 mutex 锁住
+type Pool27 struct {
+	mu   sync.Mutex // ptrs[*].state27
+	ptrs map[string]*Item27
+}
+
+func (p *Pool27) F27c(k string) {
+	p.mu.Lock()
+	p.ptrs[k].state27++
+	p.mu.Unlock()
+}
+
+func (p *Pool27) G27e(k string) {
+	p.ptrs[k].state27++
+}
+
+// 全局 mutex 不支持嵌套路径
+var mu27 sync.Mutex // stats27.hits27, items27[*].state27
+var stats27 Stats27
+var items27 []Item27
+
+// 嵌套路径拼写错误，提示出错那一段的字段
+type Cache27 struct {
+	mu    sync.Mutex // stats.hit27
+	stats Stats27
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 全局 mutex 锁多个类型的字段，不需要注释
+var registryMu28 sync.Mutex
+
+type Plugin28 struct {
+	name28 string // guarded_by: registryMu28
+}
+
+type Codec28 struct {
+	id28 int // guarded_by: test.registryMu28
+}
+
+func (p *Plugin28) F28a() {
+	registryMu28.Lock()
+	p.name28 = "a"
+	registryMu28.Unlock()
+}
+
+func (p *Plugin28) G28a() {
+	fmt.Println(p.name28)
+}
+
+func (c *Codec28) G28b() {
+	c.id28++
+}
+
+// 单例的字段 mutex 锁全局变量
+type Manager28 struct {
+	mu sync.RWMutex
+}
+
+var mgr28 = &Manager28{}
+
+var users28 = map[int]string{} // guarded_by: mgr28.mu
+
+func F28b() {
+	mgr28.mu.RLock()
+	fmt.Println(users28[1])
+	mgr28.mu.RUnlock()
+}
+
+func G28c() {
+	users28[1] = "b"
+}
+
+var bad28 int // guarded_by: noSuchMu28
+
+// 文档注释中的 guarded_by ，以及括号中的理由
+var docMu28 sync.Mutex
+
+// guarded_by: docMu28 (hot path)
+var hot28 int
+
+type Doc28 struct {
+	// 计数
+	// guarded_by: docMu28
+	count28 int
+}
+
+func G28d(d *Doc28) {
+	hot28++
+	d.count28++
+}
+
+func F28d(d *Doc28) {
+	docMu28.Lock()
+	hot28++
+	d.count28++
+	docMu28.Unlock()
+}
+
+// 嵌入的 mutex 被 guarded_by 引用，不需要注释
+type Embed28 struct {
+	sync.Mutex
+	n28 int // guarded_by: Mutex
+}
+
+func (e *Embed28) F28e() {
+	e.Lock()
+	e.n28++
+	e.Unlock()
+}
+
+func (e *Embed28) G28e() {
+	e.n28++
+}
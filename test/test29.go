@@ -0,0 +1,60 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 注释写在要锁的字段上， mutex 不需要注释
+type Conn29 struct {
+	mu      sync.Mutex
+	state29 int            // guarded_by: mu
+	buf29   []byte         `guarded_by:"mu"`
+	peers29 map[string]int `msg:"peers" guarded_by:"mu"`
+}
+
+func (c *Conn29) F29a() {
+	c.mu.Lock()
+	c.state29++
+	c.buf29 = append(c.buf29, 1)
+	c.peers29["a"] = 1
+	c.mu.Unlock()
+}
+
+func (c *Conn29) G29a() {
+	fmt.Println(c.state29, len(c.buf29))
+}
+
+func (c *Conn29) G29b() {
+	c.peers29["b"] = 2
+}
+
+// 与 mutex 注释合并
+type Pool29 struct {
+	mu     sync.Mutex // idle29
+	mu2    sync.Mutex // nolint: mutex_check
+	idle29 int        // guarded_by: mu
+	busy29 int        // guarded_by: mu
+	cnt29  int        // guarded_by: mu2
+	lost29 int        `guarded_by:"mu"` // guarded_by: mu3
+	mu3    sync.Mutex
+}
+
+func (p *Pool29) G29c() {
+	fmt.Println(p.idle29, p.busy29)
+}
+
+func (p *Pool29) H29() {
+	p.cnt29++
+}
+
+// 全局变量
+var m29 sync.Mutex // a29
+
+var a29 int // guarded_by: m29
+
+var b29 int // guarded_by: m29
+
+func G29d() {
+	fmt.Println(a29 + b29)
+}
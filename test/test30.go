@@ -0,0 +1,81 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+type Cache30 struct {
+	mu     sync.RWMutex // data30
+	data30 map[string]int
+}
+
+// getLocked 调用者需要持有 c.mu
+//
+// mutex_check: requires_read c.mu
+func (c *Cache30) getLocked(k string) int {
+	return c.data30[k]
+}
+
+// mutex_check: requires c.mu (caller holds it)
+func (c *Cache30) setLocked(k string, v int) {
+	c.data30[k] = v
+}
+
+// mutex_check: acquires c.mu
+func (c *Cache30) lock30() {
+	c.mu.Lock()
+}
+
+// mutex_check: releases c.mu
+func (c *Cache30) unlock30() {
+	c.mu.Unlock()
+}
+
+func (c *Cache30) Get30(k string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.getLocked(k)
+}
+
+func (c *Cache30) Set30(k string, v int) {
+	c.lock30()
+	c.setLocked(k, v)
+	c.unlock30()
+}
+
+func (c *Cache30) BadSet30(k string, v int) {
+	c.mu.RLock()
+	c.setLocked(k, v)
+	c.mu.RUnlock()
+}
+
+func (c *Cache30) BadGet30(k string) int {
+	return c.getLocked(k)
+}
+
+func (c *Cache30) BadUnlock30() {
+	c.unlock30()
+}
+
+// 全局 mutex
+var m30 sync.Mutex // a30
+
+var a30 int
+
+// mutex_check: requires m30
+func incLocked30() {
+	a30++
+}
+
+func F30() {
+	m30.Lock()
+	incLocked30()
+	m30.Unlock()
+	go incLocked30()
+	fmt.Println(a30)
+}
+
+// mutex_check: requires c.nope
+func (c *Cache30) bad30() {
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 命名约定，需要 --lockedpattern=Locked31$ （见 test.sh ）
+type Queue31 struct {
+	mu      sync.RWMutex // items31
+	items31 []int
+}
+
+func (q *Queue31) pushLocked31(v int) {
+	q.items31 = append(q.items31, v)
+}
+
+func (q *Queue31) lenLocked31() int {
+	return len(q.items31)
+}
+
+func (q *Queue31) Push31(v int) {
+	q.mu.Lock()
+	q.pushLocked31(v)
+	q.mu.Unlock()
+}
+
+func (q *Queue31) Len31() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.lenLocked31()
+}
+
+func (q *Queue31) BadPush31(v int) {
+	q.mu.RLock()
+	q.pushLocked31(v)
+	q.mu.RUnlock()
+}
+
+func (q *Queue31) BadLen31() {
+	fmt.Println(q.lenLocked31())
+}
+
+// 多个 mutex ，无法推断
+type Two31 struct {
+	mu1 sync.Mutex // a31
+	mu2 sync.Mutex // b31
+	a31 int
+	b31 int
+}
+
+func (t *Two31) incLocked31() {
+	t.a31++
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+// gVisor checklocks 注释
+type Conn32 struct {
+	mu sync.Mutex
+
+	// +checklocks:mu
+	state32 int
+
+	buf32 []byte // +checklocks:mu
+
+	// +checklocksignore
+	stats32 int // guarded_by: mu
+}
+
+var m32 sync.RWMutex
+
+// +checklocks:m32
+var a32 int
+
+// +checklocks:c.mu
+func (c *Conn32) setStateLocked32(v int) {
+	c.state32 = v
+}
+
+// +checklocksacquire:c.mu
+func (c *Conn32) lock32() {
+	c.mu.Lock()
+}
+
+// +checklocksrelease:c.mu
+func (c *Conn32) unlock32() {
+	c.mu.Unlock()
+}
+
+func (c *Conn32) F32() {
+	c.lock32()
+	c.setStateLocked32(1)
+	c.buf32 = nil
+	c.unlock32()
+}
+
+func (c *Conn32) G32() {
+	c.setStateLocked32(2)
+	fmt.Println(len(c.buf32))
+	fmt.Println(c.stats32)
+}
+
+// +checklocksignore
+func (c *Conn32) H32() {
+	c.state32++
+}
+
+func F32b() {
+	m32.RLock()
+	fmt.Println(a32)
+	m32.RUnlock()
+}
+
+func G32b() {
+	a32++
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 结构化的 mutex 注释
+type Session33 struct {
+	// mu 保护会话状态。
+	// protects: token33, expires33
+	mu        sync.Mutex
+	token33   string
+	expires33 int
+
+	mu2      sync.Mutex // protects: hits33, misses33 (统计用，允许近似)
+	hits33   int
+	misses33 int
+
+	// 拼写错误、非法的名字，不影响后面的变量
+	mu3       sync.Mutex // a33, see doc, countr33, b33
+	a33       int
+	b33       int
+	counter33 int
+
+	mu4 sync.Mutex // c33 (未闭合的理由
+	c33 int
+}
+
+func (s *Session33) G33a() {
+	fmt.Println(s.token33)
+}
+
+func (s *Session33) G33b() {
+	s.misses33++
+}
+
+func (s *Session33) G33c() {
+	s.b33++
+}
+
+func (s *Session33) G33d() {
+	fmt.Println(s.c33)
+}
+
+// protects: d33
+var m33 sync.Mutex
+
+var d33 int
+
+var m33b sync.Mutex // d33b, e33x
+
+var d33b int
+
+var e33 int
+
+func G33e() {
+	d33++
+}
+
+func G33f() {
+	d33b++
+	e33++
+}
+
+// 嵌入泛型类型的结构体
+type Box33[T any] struct {
+	v T
+}
+
+type Boxed33 struct {
+	Box33[int]
+	mu  sync.Mutex // n33 (counter)
+	n33 int
+}
+
+func (b *Boxed33) G33g() {
+	b.n33++
+}
@@ -0,0 +1,27 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// 要锁的变量声明在同一个包的其他文件中（ test35.go ）
+var stateMu34 sync.Mutex // cache35, test.hits35
+
+// 要锁的变量在 import 的包中
+var argsMu34 sync.Mutex // os.Args
+
+func F34() {
+	stateMu34.Lock()
+	cache35["a"] = 1
+	hits35++
+	stateMu34.Unlock()
+	argsMu34.Lock()
+	fmt.Println(os.Args)
+	argsMu34.Unlock()
+}
+
+func G34() {
+	fmt.Println(len(os.Args))
+}
@@ -0,0 +1,15 @@
+package test
+
+import "fmt"
+
+var cache35 = map[string]int{}
+
+var hits35 int
+
+func G35a() {
+	cache35["b"] = 2
+}
+
+func G35b() {
+	fmt.Println(hits35)
+}
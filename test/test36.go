@@ -0,0 +1,31 @@
+package test
+
+import "sync"
+
+// 匿名结构体全局变量中的同名字段，各自检查
+var cfgA36 struct {
+	mu sync.Mutex // m
+	m  map[string]int
+}
+
+var cfgB36 struct {
+	mu sync.Mutex // m
+	m  map[string]int
+}
+
+func SetA36(k string) {
+	cfgA36.m[k] = 1
+}
+
+func SetB36(k string) {
+	cfgB36.m[k] = 2
+}
+
+func OK36(k string) {
+	cfgA36.mu.Lock()
+	cfgA36.m[k] = 1
+	cfgA36.mu.Unlock()
+	cfgB36.mu.Lock()
+	cfgB36.m[k] = 2
+	cfgB36.mu.Unlock()
+}
@@ -0,0 +1,49 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fananchong/go_mutex_check/test/reg37"
+)
+
+// 由 import 的包中的 mutex 锁住
+var order37 []string // guarded_by: reg37.Mu
+
+type Entry37 struct {
+	name37 string `guarded_by:"reg37.Mu"`
+}
+
+func Register37(e *Entry37, name string) {
+	reg37.Mu.Lock()
+	reg37.Plugins[name] = len(order37)
+	order37 = append(order37, name)
+	e.name37 = name
+	reg37.Mu.Unlock()
+}
+
+func List37(e *Entry37) {
+	fmt.Println(order37, e.name37)
+}
+
+// 约定中的 mutex 在 import 的包中
+// mutex_check: requires reg37.Mu
+func appendLocked37(name string) {
+	order37 = append(order37, name)
+}
+
+func Add37(name string) {
+	reg37.Mu.Lock()
+	appendLocked37(name)
+	reg37.Mu.Unlock()
+}
+
+func BadAdd37(name string) {
+	appendLocked37(name)
+}
+
+// 参数本身是 mutex ，不支持
+// mutex_check: requires m
+func withMu37(m *sync.Mutex) {
+	m.Unlock()
+}
@@ -1,17 +1,42 @@
 package test
 
-// type b struct {
-// 	sync.Mutex // a
-// 	a          int
-// }
-
-// func (b1 *b) f1() {
-// 	// b1.Lock()
-// 	// defer b1.Unlock()
-// 	fmt.Print(b1.a)
-// }
-
-// func init() {
-// 	b1 := b{}
-// 	b1.f1()
-// }
+import (
+	"fmt"
+	"sync"
+)
+
+type B7 struct {
+	sync.Mutex // a7
+	a7         int
+}
+
+func (b1 *B7) F7() {
+	b1.Lock()
+	defer b1.Unlock()
+	fmt.Print(b1.a7)
+}
+
+func (b1 *B7) G7() {
+	fmt.Print(b1.a7)
+}
+
+// 多层嵌入， Lock 来自 C7.B7.Mutex
+type C7 struct {
+	B7
+	c7 int
+}
+
+func (c *C7) H7() {
+	c.Lock()
+	c.a7++
+	c.Unlock()
+}
+
+func (c *C7) I7() {
+	c.a7++
+}
+
+func init() {
+	b1 := B7{}
+	b1.F7()
+}
@@ -1,17 +1,41 @@
 package test
 
-// type b struct {
-// 	*sync.Mutex // a8
-// 	a8          int
-// }
-
-// func (b1 *b) f1() {
-// 	b1.Lock()
-// 	// defer b1.Unlock()
-// 	fmt.Print(b1.a8)
-// }
-
-// func init() {
-// 	b1 := b{}
-// 	b1.f1()
-// }
+import (
+	"fmt"
+	"sync"
+)
+
+type B8 struct {
+	*sync.Mutex // a8
+	a8          int
+}
+
+func (b1 *B8) F8() {
+	b1.Lock()
+	defer b1.Unlock()
+	fmt.Print(b1.a8)
+}
+
+func (b1 *B8) G8() {
+	fmt.Print(b1.a8)
+}
+
+// 多层嵌入，嵌入的是指针
+type C8 struct {
+	*B8
+}
+
+func (c *C8) H8() {
+	c.Lock()
+	c.a8++
+	c.Unlock()
+}
+
+func (c *C8) I8() {
+	c.a8++
+}
+
+func init() {
+	b1 := B8{Mutex: &sync.Mutex{}}
+	b1.F8()
+}